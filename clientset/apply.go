@@ -0,0 +1,188 @@
+package clientset
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/xlcbingo1999/example-client-go/stack"
+)
+
+// ApplyResult 描述 apply 模式下单个对象的处理结果
+type ApplyResult string
+
+const (
+	Created   ApplyResult = "created"
+	Updated   ApplyResult = "updated"
+	Unchanged ApplyResult = "unchanged"
+	// Deployment 的 selector 不可修改, selector 发生漂移时只能删除后重建
+	Replaced ApplyResult = "replaced"
+)
+
+// apply 创建缺失的对象, 并原地更新发生漂移的对象, 可以安全地重复执行
+func apply(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+
+	result, err := applyNamespace(ctx, clientset, newNamespace(spec))
+	if err != nil {
+		return err
+	}
+	log.Printf("namespace/%s %s\n", spec.Namespace, result)
+
+	result, err = applyDeployment(ctx, clientset, stack.NewDeployment(spec.app()))
+	if err != nil {
+		return err
+	}
+	log.Printf("deployment/%s %s\n", spec.DeploymentName, result)
+
+	result, err = applyService(ctx, clientset, stack.NewService(spec.app()))
+	if err != nil {
+		return err
+	}
+	log.Printf("service/%s %s\n", spec.ServiceName, result)
+	return nil
+}
+
+func applyNamespace(ctx context.Context, clientset kubernetes.Interface, desired *apiv1.Namespace) (ApplyResult, error) {
+	client := clientset.CoreV1().Namespaces()
+
+	_, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+	if err == nil {
+		return Unchanged, nil
+	}
+	if !apierrors.IsNotFound(err) {
+		return "", fmt.Errorf("get namespace %s: %w", desired.Name, err)
+	}
+
+	if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+		return "", fmt.Errorf("create namespace %s: %w", desired.Name, err)
+	}
+	return Created, nil
+}
+
+func applyDeployment(ctx context.Context, clientset kubernetes.Interface, desired *appsv1.Deployment) (ApplyResult, error) {
+	client := clientset.AppsV1().Deployments(desired.Namespace)
+
+	result := Unchanged
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			result = Created
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+			log.Printf("deployment/%s selector drifted: %v -> %v\n", desired.Name,
+				metav1.FormatLabelSelector(current.Spec.Selector), metav1.FormatLabelSelector(desired.Spec.Selector))
+			if err := replaceDeployment(ctx, clientset, desired); err != nil {
+				return err
+			}
+			result = Replaced
+			return nil
+		}
+
+		drift := stack.DeploymentDrift(current, desired)
+		if len(drift) == 0 {
+			result = Unchanged
+			return nil
+		}
+		log.Printf("deployment/%s drifted: %s\n", desired.Name, strings.Join(drift, ", "))
+
+		current.Labels = desired.Labels
+		current.Spec.Replicas = desired.Spec.Replicas
+		current.Spec.Template.Labels = desired.Spec.Template.Labels
+		current.Spec.Template.Spec.Containers = desired.Spec.Template.Spec.Containers
+		if _, err := client.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		result = Updated
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("apply deployment %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+	return result, nil
+}
+
+// replaceDeployment 删除旧的 deployment, 等它真正消失后再重新创建
+func replaceDeployment(ctx context.Context, clientset kubernetes.Interface, desired *appsv1.Deployment) error {
+	client := clientset.AppsV1().Deployments(desired.Namespace)
+
+	foreground := metav1.DeletePropagationForeground
+	err := client.Delete(ctx, desired.Name, metav1.DeleteOptions{PropagationPolicy: &foreground})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, 2*time.Minute, true, func(ctx context.Context) (bool, error) {
+		_, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Create(ctx, desired, metav1.CreateOptions{})
+	return err
+}
+
+func applyService(ctx context.Context, clientset kubernetes.Interface, desired *apiv1.Service) (ApplyResult, error) {
+	client := clientset.CoreV1().Services(desired.Namespace)
+
+	result := Unchanged
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		current, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			if _, err := client.Create(ctx, desired, metav1.CreateOptions{}); err != nil {
+				return err
+			}
+			result = Created
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		drift := stack.ServiceDrift(current, desired)
+		if len(drift) == 0 {
+			result = Unchanged
+			return nil
+		}
+		log.Printf("service/%s drifted: %s\n", desired.Name, strings.Join(drift, ", "))
+
+		// 只覆盖我们关心的字段, clusterIP 等由 apiserver 分配的字段保持不变
+		current.Labels = desired.Labels
+		current.Spec.Type = desired.Spec.Type
+		current.Spec.Selector = desired.Spec.Selector
+		current.Spec.Ports = desired.Spec.Ports
+		if _, err := client.Update(ctx, current, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+		result = Updated
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("apply service %s/%s: %w", desired.Namespace, desired.Name, err)
+	}
+	return result, nil
+}
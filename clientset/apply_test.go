@@ -0,0 +1,121 @@
+package clientset
+
+import (
+	"context"
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/xlcbingo1999/example-client-go/stack"
+)
+
+func testSpec() *Spec {
+	spec := DefaultSpec()
+	spec.Namespace = "default"
+	spec.Limits = map[string]string{"memory": "256Mi"}
+	return &spec
+}
+
+// applyTwice 先用 base 创建 deployment 和 service, 再用 changed 修改后的 spec 执行一次 apply
+func applyTwice(t *testing.T, changed func(spec *Spec)) (*fake.Clientset, ApplyResult, ApplyResult) {
+	t.Helper()
+	ctx := context.Background()
+	clientset := fake.NewSimpleClientset()
+	spec := testSpec()
+	if _, err := applyDeployment(ctx, clientset, stack.NewDeployment(spec.app())); err != nil {
+		t.Fatalf("create deployment: %v", err)
+	}
+	if _, err := applyService(ctx, clientset, stack.NewService(spec.app())); err != nil {
+		t.Fatalf("create service: %v", err)
+	}
+
+	changed(spec)
+	deployment, err := applyDeployment(ctx, clientset, stack.NewDeployment(spec.app()))
+	if err != nil {
+		t.Fatalf("apply deployment: %v", err)
+	}
+	service, err := applyService(ctx, clientset, stack.NewService(spec.app()))
+	if err != nil {
+		t.Fatalf("apply service: %v", err)
+	}
+	return clientset, deployment, service
+}
+
+func TestApplyUnchanged(t *testing.T) {
+	_, deployment, service := applyTwice(t, func(spec *Spec) {})
+	if deployment != Unchanged || service != Unchanged {
+		t.Errorf("apply results = %s, %s, want unchanged", deployment, service)
+	}
+}
+
+func TestApplyUnchangedWhenRequestsDefaultToLimits(t *testing.T) {
+	ctx := context.Background()
+	spec := testSpec()
+	spec.Requests = map[string]string{"cpu": "100m"}
+	// apiserver 把只设置了 limits 的资源的 requests 默认为 limits
+	existing := stack.NewDeployment(spec.app())
+	existing.Spec.Template.Spec.Containers[0].Resources.Requests[apiv1.ResourceMemory] = resource.MustParse("256Mi")
+	clientset := fake.NewSimpleClientset(existing)
+
+	result, err := applyDeployment(ctx, clientset, stack.NewDeployment(spec.app()))
+	if err != nil {
+		t.Fatalf("apply deployment: %v", err)
+	}
+	if result != Unchanged {
+		t.Errorf("apply result = %s, want unchanged", result)
+	}
+}
+
+func TestApplyUpdatesEnv(t *testing.T) {
+	clientset, deployment, _ := applyTwice(t, func(spec *Spec) {
+		spec.Env = map[string]string{"FOO": "bar"}
+	})
+	if deployment != Updated {
+		t.Fatalf("deployment apply result = %s, want updated", deployment)
+	}
+	got, err := clientset.AppsV1().Deployments("default").Get(context.Background(), DEPLOYMENT_NAME, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	env := got.Spec.Template.Spec.Containers[0].Env
+	if len(env) != 1 || env[0].Name != "FOO" || env[0].Value != "bar" {
+		t.Errorf("env = %v, want FOO=bar", env)
+	}
+}
+
+func TestApplyUpdatesRequests(t *testing.T) {
+	clientset, deployment, _ := applyTwice(t, func(spec *Spec) {
+		spec.Requests["cpu"] = "200m"
+	})
+	if deployment != Updated {
+		t.Fatalf("deployment apply result = %s, want updated", deployment)
+	}
+	got, err := clientset.AppsV1().Deployments("default").Get(context.Background(), DEPLOYMENT_NAME, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get deployment: %v", err)
+	}
+	cpu := got.Spec.Template.Spec.Containers[0].Resources.Requests[apiv1.ResourceCPU]
+	if cpu.Cmp(resource.MustParse("200m")) != 0 {
+		t.Errorf("cpu request = %s, want 200m", cpu.String())
+	}
+}
+
+func TestApplyUpdatesLabels(t *testing.T) {
+	clientset, deployment, service := applyTwice(t, func(spec *Spec) {
+		spec.Labels["tier"] = "web"
+	})
+	// 模板标签变化也会改变 selector, deployment 需要重建
+	if deployment != Replaced || service != Updated {
+		t.Fatalf("apply results = %s, %s, want replaced, updated", deployment, service)
+	}
+	got, err := clientset.CoreV1().Services("default").Get(context.Background(), SERVICE_NAME, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get service: %v", err)
+	}
+	if got.Labels["tier"] != "web" {
+		t.Errorf("service labels = %v, want tier=web", got.Labels)
+	}
+}
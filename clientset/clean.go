@@ -0,0 +1,172 @@
+package clientset
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// deleteOptions 根据 --propagation/--grace-period 构建删除参数
+func deleteOptions() (metav1.DeleteOptions, error) {
+	opts := metav1.DeleteOptions{}
+
+	switch strings.ToLower(Propagation) {
+	case "":
+	case "background":
+		opts.PropagationPolicy = ptr.To(metav1.DeletePropagationBackground)
+	case "foreground":
+		opts.PropagationPolicy = ptr.To(metav1.DeletePropagationForeground)
+	case "orphan":
+		opts.PropagationPolicy = ptr.To(metav1.DeletePropagationOrphan)
+	default:
+		return opts, &exitcode.UsageError{Err: fmt.Errorf("invalid propagation policy %q, must be background, foreground or orphan", Propagation)}
+	}
+
+	if GracePeriod >= 0 {
+		opts.GracePeriodSeconds = ptr.To(GracePeriod)
+	}
+	return opts, nil
+}
+
+// clean 按 service -> deployment -> namespace 的顺序删除, 已经不存在的对象直接跳过
+func clean(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+	namespace := spec.Namespace
+
+	opts, err := deleteOptions()
+	if err != nil {
+		return err
+	}
+
+	err = clientset.CoreV1().Services(namespace).Delete(ctx, spec.ServiceName, opts)
+	if err := skipNotFound(err, "service", namespace+"/"+spec.ServiceName); err != nil {
+		return err
+	}
+
+	err = clientset.AppsV1().Deployments(namespace).Delete(ctx, spec.DeploymentName, opts)
+	if err := skipNotFound(err, "deployment", namespace+"/"+spec.DeploymentName); err != nil {
+		return err
+	}
+
+	err = clientset.CoreV1().Namespaces().Delete(ctx, namespace, opts)
+	if err := skipNotFound(err, "namespace", namespace); err != nil {
+		return err
+	}
+
+	if !Wait {
+		return nil
+	}
+	return waitForNamespaceGone(ctx, clientset, namespace, Timeout)
+}
+
+func skipNotFound(err error, kind, name string) error {
+	if err == nil {
+		log.Printf("Delete %s %s\n", kind, name)
+		return nil
+	}
+	if apierrors.IsNotFound(err) {
+		log.Printf("Skip %s %s: not found\n", kind, name)
+		return nil
+	}
+	return fmt.Errorf("delete %s %s: %w", kind, name, err)
+}
+
+// waitForNamespaceGone 阻塞直到 namespace 被彻底删除
+// 超时后会报告卡住删除的 finalizer 和 namespace 中残留的资源
+func waitForNamespaceGone(ctx context.Context, clientset kubernetes.Interface, namespace string, timeout time.Duration) error {
+	log.Printf("Waiting up to %v for namespace %s to be deleted\n", timeout, namespace)
+
+	var last *apiv1.Namespace
+	err := wait.PollUntilContextTimeout(ctx, 2*time.Second, timeout, true, func(ctx context.Context) (bool, error) {
+		ns, err := clientset.CoreV1().Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		last = ns
+		return false, nil
+	})
+	if err == nil {
+		log.Printf("Namespace %s deleted\n", namespace)
+		return nil
+	}
+	if last == nil || !wait.Interrupted(err) {
+		return fmt.Errorf("wait for namespace %s deletion: %w", namespace, err)
+	}
+
+	// 超时了, 收集诊断信息
+	var problems []string
+	if len(last.Spec.Finalizers) > 0 {
+		problems = append(problems, fmt.Sprintf("spec.finalizers=%v", last.Spec.Finalizers))
+	}
+	if len(last.Finalizers) > 0 {
+		problems = append(problems, fmt.Sprintf("metadata.finalizers=%v", last.Finalizers))
+	}
+	for _, cond := range last.Status.Conditions {
+		if cond.Status == apiv1.ConditionTrue {
+			problems = append(problems, fmt.Sprintf("%s: %s", cond.Type, cond.Message))
+		}
+	}
+	problems = append(problems, remainingResources(clientset, namespace)...)
+
+	for _, p := range problems {
+		log.Printf("Namespace %s: %s\n", namespace, p)
+	}
+	return fmt.Errorf("namespace %s still %s after %v (%s): %w",
+		namespace, last.Status.Phase, timeout, strings.Join(problems, "; "), err)
+}
+
+// remainingResources 列出 namespace 中还没有被删除的常见资源
+func remainingResources(clientset kubernetes.Interface, namespace string) []string {
+	ctx := context.TODO()
+	opts := metav1.ListOptions{}
+
+	lists := []struct {
+		kind string
+		list func() (runtime.Object, error)
+	}{
+		{"deployments", func() (runtime.Object, error) { return clientset.AppsV1().Deployments(namespace).List(ctx, opts) }},
+		{"replicasets", func() (runtime.Object, error) { return clientset.AppsV1().ReplicaSets(namespace).List(ctx, opts) }},
+		{"pods", func() (runtime.Object, error) { return clientset.CoreV1().Pods(namespace).List(ctx, opts) }},
+		{"services", func() (runtime.Object, error) { return clientset.CoreV1().Services(namespace).List(ctx, opts) }},
+	}
+
+	var remaining []string
+	for _, l := range lists {
+		list, err := l.list()
+		if err != nil {
+			remaining = append(remaining, fmt.Sprintf("list %s: %v", l.kind, err))
+			continue
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			continue
+		}
+
+		var names []string
+		for _, item := range items {
+			if accessor, err := meta.Accessor(item); err == nil {
+				names = append(names, accessor.GetName())
+			}
+		}
+		if len(names) > 0 {
+			remaining = append(remaining, fmt.Sprintf("remaining %s: %s", l.kind, strings.Join(names, ",")))
+		}
+	}
+	return remaining
+}
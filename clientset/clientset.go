@@ -2,21 +2,19 @@ package clientset
 
 import (
 	"context"
-	"flag"
+	"fmt"
 	"log"
-	"path/filepath"
+	"time"
 
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/stack"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
-	"k8s.io/utils/ptr"
 
-	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// 默认部署的资源名称, 可以通过命令行参数或 spec 文件修改
 const (
 	NAMESPACE       = "test-clientset"
 	DEPLOYMENT_NAME = "client-test-deployment"
@@ -24,168 +22,119 @@ const (
 )
 
 var (
-	Kubeconfig string
-	Operate    string
-)
+	Operate string
 
-func RunClientSet() {
-	var err error
-	var config *rest.Config
+	// 等待操作完成(例如 clean 时等待 namespace 真正被删除)
+	Wait    bool
+	Timeout time.Duration
 
-	if home := homedir.HomeDir(); home != "" {
-		Kubeconfig = filepath.Join(home, ".kube", "config")
-	} else {
-		panic(err.Error())
-	}
-
-	flag.Parse()
+	// clean 使用的删除参数
+	Propagation string
+	// 优雅删除的秒数, 小于 0 时使用对象自身的默认值
+	GracePeriod int64
+)
 
-	// 首先使用 inCluster 模式(需要去配置对应的 RBAC 权限，默认的sa是default->是没有获取deployments的List权限)
-	if config, err = rest.InClusterConfig(); err != nil {
-		// 使用 KubeConfig 文件创建集群配置 Config 对象
-		if config, err = clientcmd.BuildConfigFromFlags("", Kubeconfig); err != nil {
-			panic(err.Error())
-		}
+func RunClientSet() error {
+	// 在访问 apiserver 之前先校验参数
+	spec, err := loadSpec()
+	if err != nil {
+		return err
 	}
-	// 创建 ClientSet 实例
-	clientset, err := kubernetes.NewForConfig(config)
+
+	// 创建 ClientSet 实例, 集群连接参数来自 --kubeconfig/--context/--in-cluster
+	clientset, err := connection.NewClientSet()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	log.Printf("operation is %v\n", Operate)
+	switch Operate {
 	// 如果要执行清理操作
-	if Operate == "clean" {
-		clean(clientset)
-	} else if Operate == "list" {
-		listPod(clientset)
+	case "clean":
+		return clean(clientset, spec)
+	case "list":
+		return listPod(clientset, spec.Namespace)
+	// 对已经存在的 deployment 执行扩缩容/重启/回滚
+	case "scale":
+		return scale(clientset, spec)
+	case "restart":
+		return restart(clientset, spec)
+	case "history":
+		return history(clientset, spec)
+	case "rollback":
+		return rollback(clientset, spec)
+	}
+
+	// 提交之前检查生成的 deployment 和 service
+	if err := checkStack(spec); err != nil {
+		return err
+	}
+
+	if Operate == "apply" {
+		if err := apply(clientset, spec); err != nil {
+			return err
+		}
 	} else {
 		// 创建namespace
-		createNamespace(clientset)
+		if err := createNamespace(clientset, spec); err != nil {
+			return err
+		}
 
 		// 创建deployment
-		createDeployment(clientset)
+		if err := createDeployment(clientset, spec); err != nil {
+			return err
+		}
 
 		// 创建service
-		createService(clientset)
-	}
-
-}
-
-func clean(clientset *kubernetes.Clientset) {
-	emptyDeleteOptions := metav1.DeleteOptions{}
-	if err := clientset.CoreV1().Services(NAMESPACE).Delete(context.TODO(), SERVICE_NAME, emptyDeleteOptions); err != nil {
-		panic(err.Error())
-	}
-
-	if err := clientset.AppsV1().Deployments(NAMESPACE).Delete(context.TODO(), DEPLOYMENT_NAME, emptyDeleteOptions); err != nil {
-		panic(err.Error())
+		if err := createService(clientset, spec); err != nil {
+			return err
+		}
 	}
 
-	if err := clientset.CoreV1().Namespaces().Delete(context.TODO(), NAMESPACE, emptyDeleteOptions); err != nil {
-		panic(err.Error())
+	if !Wait {
+		return nil
 	}
+	// 等待 pod 真正运行起来
+	return waitForRollout(context.TODO(), clientset, spec.Namespace, spec.DeploymentName, Timeout)
 }
 
-func createNamespace(clientset *kubernetes.Clientset) {
-	namespaceClient := clientset.CoreV1().Namespaces()
-	namespace := &apiv1.Namespace{
+func newNamespace(spec *Spec) *apiv1.Namespace {
+	return &apiv1.Namespace{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: NAMESPACE,
+			Name: spec.Namespace,
 		},
 	}
+}
+
+func createNamespace(clientset kubernetes.Interface, spec *Spec) error {
+	namespaceClient := clientset.CoreV1().Namespaces()
 
-	result, err := namespaceClient.Create(context.TODO(), namespace, metav1.CreateOptions{})
+	result, err := namespaceClient.Create(context.TODO(), newNamespace(spec), metav1.CreateOptions{})
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("create namespace %s: %w", spec.Namespace, err)
 	}
 	log.Println("Create ns ", result.GetName())
+	return nil
 }
 
-func createService(clientset *kubernetes.Clientset) {
-	serviceClient := clientset.CoreV1().Services(NAMESPACE)
-	service := &apiv1.Service{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: SERVICE_NAME,
-		},
-		Spec: apiv1.ServiceSpec{
-			Ports: []apiv1.ServicePort{{
-				Name:     "http",
-				Port:     8080,
-				NodePort: 30480,
-			},
-			},
-			Selector: map[string]string{
-				"app": "tomcat",
-			},
-			Type: apiv1.ServiceTypeNodePort,
-		},
-	}
+func createService(clientset kubernetes.Interface, spec *Spec) error {
+	serviceClient := clientset.CoreV1().Services(spec.Namespace)
 
-	result, err := serviceClient.Create(context.TODO(), service, metav1.CreateOptions{})
+	result, err := serviceClient.Create(context.TODO(), stack.NewService(spec.app()), metav1.CreateOptions{})
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("create service %s/%s: %w", spec.Namespace, spec.ServiceName, err)
 	}
 	log.Println("Create SVC ", result.GetName())
+	return nil
 }
 
-func createDeployment(clientset *kubernetes.Clientset) {
-	deploymentClient := clientset.AppsV1().Deployments(NAMESPACE)
-	deployment := &appsv1.Deployment{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: DEPLOYMENT_NAME,
-		},
-		Spec: appsv1.DeploymentSpec{
-			Replicas: ptr.To(int32(2)),
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app": "tomcat",
-				},
-			},
-			Template: apiv1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app": "tomcat",
-					},
-				},
-				Spec: apiv1.PodSpec{
-					Containers: []apiv1.Container{{
-						Name:            "tomcat",
-						Image:           "tomcat:8.0.18-jre8",
-						ImagePullPolicy: "IfNotPresent",
-						Ports: []apiv1.ContainerPort{{
-							Name:          "http",
-							Protocol:      apiv1.ProtocolSCTP,
-							ContainerPort: 8080,
-						},
-						},
-					},
-					},
-				},
-			},
-		},
-	}
+func createDeployment(clientset kubernetes.Interface, spec *Spec) error {
+	deploymentClient := clientset.AppsV1().Deployments(spec.Namespace)
 
-	result, err := deploymentClient.Create(context.TODO(), deployment, metav1.CreateOptions{})
+	result, err := deploymentClient.Create(context.TODO(), stack.NewDeployment(spec.app()), metav1.CreateOptions{})
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("create deployment %s/%s: %w", spec.Namespace, spec.DeploymentName, err)
 	}
 	log.Println("Create deployment ", result.GetName())
-}
-
-func listPod(clientset *kubernetes.Clientset) {
-	// 设置 list options
-	listOptions := metav1.ListOptions{
-		LabelSelector: "",
-		FieldSelector: "",
-	}
-
-	// 获取 default 命名空间下的 pod 列表
-	pods, err := clientset.CoreV1().Pods(NAMESPACE).List(context.TODO(), listOptions)
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, pod := range pods.Items {
-		log.Println(pod.Name)
-	}
+	return nil
 }
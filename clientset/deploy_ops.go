@@ -0,0 +1,195 @@
+package clientset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+const (
+	// kubectl rollout restart 使用的注解, 修改它会让 pod 模板发生变化从而触发滚动更新
+	restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// rollback 的目标版本, 0 表示上一个版本
+var ToRevision int64
+
+// scale 通过 scale 子资源修改副本数, 然后等待 rollout 完成
+func scale(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+	client := clientset.AppsV1().Deployments(spec.Namespace)
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		s, err := client.GetScale(ctx, spec.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if s.Spec.Replicas == spec.Replicas {
+			log.Printf("Deployment %s already has %d replicas\n", spec.DeploymentName, spec.Replicas)
+			return nil
+		}
+
+		log.Printf("Scaling deployment %s from %d to %d replicas\n", spec.DeploymentName, s.Spec.Replicas, spec.Replicas)
+		s.Spec.Replicas = spec.Replicas
+		_, err = client.UpdateScale(ctx, spec.DeploymentName, s, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("scale deployment %s/%s: %w", spec.Namespace, spec.DeploymentName, err)
+	}
+	return waitForRollout(ctx, clientset, spec.Namespace, spec.DeploymentName, Timeout)
+}
+
+// restart 修改 pod 模板上的注解触发滚动重启, 和 kubectl rollout restart 一样
+func restart(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = clientset.AppsV1().Deployments(spec.Namespace).Patch(ctx, spec.DeploymentName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("restart deployment %s/%s: %w", spec.Namespace, spec.DeploymentName, err)
+	}
+	log.Printf("Deployment %s restarted\n", spec.DeploymentName)
+	return waitForRollout(ctx, clientset, spec.Namespace, spec.DeploymentName, Timeout)
+}
+
+// listRevisions 返回 deployment 拥有的 ReplicaSet, 按版本号从小到大排序
+func listRevisions(ctx context.Context, clientset kubernetes.Interface, d *appsv1.Deployment) ([]*appsv1.ReplicaSet, error) {
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	list, err := clientset.AppsV1().ReplicaSets(d.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, fmt.Errorf("list replicasets of deployment %s: %w", d.Name, err)
+	}
+
+	objs := make([]interface{}, 0, len(list.Items))
+	for i := range list.Items {
+		objs = append(objs, &list.Items[i])
+	}
+	return ownedReplicaSets(d, objs), nil
+}
+
+// history 打印 deployment 的历史版本
+func history(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+
+	d, err := clientset.AppsV1().Deployments(spec.Namespace).Get(ctx, spec.DeploymentName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get deployment %s/%s: %w", spec.Namespace, spec.DeploymentName, err)
+	}
+	revisions, err := listRevisions(ctx, clientset, d)
+	if err != nil {
+		return err
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 8, 3, ' ', 0)
+	fmt.Fprintln(tw, "REVISION\tREPLICASET\tREPLICAS\tIMAGES\tCHANGE-CAUSE")
+	for _, rs := range revisions {
+		fmt.Fprintf(tw, "%d\t%s\t%d\t%s\t%s\n", revisionOf(rs), rs.Name, rs.Status.Replicas,
+			strings.Join(imagesOf(&rs.Spec.Template), ","), orNone(rs.Annotations[changeCauseAnnotation]))
+	}
+	return tw.Flush()
+}
+
+func imagesOf(template *apiv1.PodTemplateSpec) []string {
+	var images []string
+	for _, c := range template.Spec.Containers {
+		images = append(images, c.Image)
+	}
+	return images
+}
+
+// rollback 把 deployment 的 pod 模板恢复成指定版本的 ReplicaSet 的模板
+func rollback(clientset kubernetes.Interface, spec *Spec) error {
+	ctx := context.TODO()
+	client := clientset.AppsV1().Deployments(spec.Namespace)
+
+	var target int64
+	var skipped bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		d, err := client.Get(ctx, spec.DeploymentName, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		revisions, err := listRevisions(ctx, clientset, d)
+		if err != nil {
+			return err
+		}
+
+		rs, err := findRevision(revisions, ToRevision)
+		if err != nil {
+			return err
+		}
+		target = revisionOf(rs)
+
+		// ReplicaSet 的模板多了 deployment controller 加上的 pod-template-hash 标签
+		template := rs.Spec.Template.DeepCopy()
+		delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		if apiequality.Semantic.DeepEqual(template, &d.Spec.Template) {
+			skipped = true
+			return nil
+		}
+
+		d.Spec.Template = *template
+		_, err = client.Update(ctx, d, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("rollback deployment %s/%s: %w", spec.Namespace, spec.DeploymentName, err)
+	}
+
+	if skipped {
+		log.Printf("Skipped rollback of deployment %s: current template already matches revision %d\n", spec.DeploymentName, target)
+		return nil
+	}
+	log.Printf("Deployment %s rolled back to revision %d\n", spec.DeploymentName, target)
+	return waitForRollout(ctx, clientset, spec.Namespace, spec.DeploymentName, Timeout)
+}
+
+// findRevision 找到指定版本的 ReplicaSet, revision 为 0 时返回上一个版本
+func findRevision(revisions []*appsv1.ReplicaSet, revision int64) (*appsv1.ReplicaSet, error) {
+	if revision == 0 {
+		if len(revisions) < 2 {
+			return nil, fmt.Errorf("no previous revision to roll back to")
+		}
+		return revisions[len(revisions)-2], nil
+	}
+
+	for _, rs := range revisions {
+		if revisionOf(rs) == revision {
+			return rs, nil
+		}
+	}
+	return nil, apierrors.NewNotFound(appsv1.Resource("replicasets"), fmt.Sprintf("revision %d", revision))
+}
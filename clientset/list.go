@@ -0,0 +1,189 @@
+package clientset
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// list 模式使用的参数
+var (
+	LabelSelector string
+	FieldSelector string
+	AllNamespaces bool
+	// 每页的数量, 通过 Limit/Continue 分页获取, 0 表示一次取完
+	PageSize int64
+	// table, wide, json, yaml 或 name
+	Output string
+)
+
+func listPod(clientset kubernetes.Interface, namespace string) error {
+	if _, err := labels.Parse(LabelSelector); err != nil {
+		return &exitcode.UsageError{Err: fmt.Errorf("invalid label selector: %w", err)}
+	}
+	if _, err := fields.ParseSelector(FieldSelector); err != nil {
+		return &exitcode.UsageError{Err: fmt.Errorf("invalid field selector: %w", err)}
+	}
+	switch Output {
+	case "table", "wide", "json", "yaml", "name":
+	default:
+		return &exitcode.UsageError{Err: fmt.Errorf("unsupported output format %q, must be table, wide, json, yaml or name", Output)}
+	}
+
+	// 空字符串表示所有 namespace
+	if AllNamespaces {
+		namespace = apiv1.NamespaceAll
+	}
+
+	// 设置 list options
+	listOptions := metav1.ListOptions{
+		LabelSelector: LabelSelector,
+		FieldSelector: FieldSelector,
+		Limit:         PageSize,
+	}
+
+	// 分页获取指定命名空间下的 pod 列表
+	var pods []apiv1.Pod
+	for {
+		page, err := clientset.CoreV1().Pods(namespace).List(context.TODO(), listOptions)
+		if err != nil {
+			return fmt.Errorf("list pods in %q: %w", namespace, err)
+		}
+		pods = append(pods, page.Items...)
+		if page.Continue == "" {
+			break
+		}
+		listOptions.Continue = page.Continue
+	}
+
+	return printPods(os.Stdout, pods, Output, AllNamespaces)
+}
+
+func printPods(w io.Writer, pods []apiv1.Pod, output string, withNamespace bool) error {
+	switch output {
+	case "name":
+		for _, pod := range pods {
+			fmt.Fprintf(w, "pod/%s\n", pod.Name)
+		}
+		return nil
+	case "json", "yaml":
+		list := &apiv1.PodList{
+			TypeMeta: metav1.TypeMeta{Kind: "List", APIVersion: "v1"},
+		}
+		for _, pod := range pods {
+			pod.TypeMeta = metav1.TypeMeta{Kind: "Pod", APIVersion: "v1"}
+			list.Items = append(list.Items, pod)
+		}
+
+		var data []byte
+		var err error
+		if output == "json" {
+			data, err = json.MarshalIndent(list, "", "    ")
+			data = append(data, '\n')
+		} else {
+			data, err = yaml.Marshal(list)
+		}
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	header := []string{"NAME", "READY", "STATUS", "RESTARTS", "AGE"}
+	if withNamespace {
+		header = append([]string{"NAMESPACE"}, header...)
+	}
+	if output == "wide" {
+		header = append(header, "IP", "NODE")
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, pod := range pods {
+		ready, total, restarts := containerCounts(&pod)
+		row := []string{
+			pod.Name,
+			fmt.Sprintf("%d/%d", ready, total),
+			podStatus(&pod),
+			fmt.Sprintf("%d", restarts),
+			duration.HumanDuration(time.Since(pod.CreationTimestamp.Time)),
+		}
+		if withNamespace {
+			row = append([]string{pod.Namespace}, row...)
+		}
+		if output == "wide" {
+			row = append(row, orNone(pod.Status.PodIP), orNone(pod.Spec.NodeName))
+		}
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	return tw.Flush()
+}
+
+// containerCounts 返回就绪的容器数, 容器总数和重启次数之和
+func containerCounts(pod *apiv1.Pod) (ready, total int, restarts int32) {
+	total = len(pod.Spec.Containers)
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Ready {
+			ready++
+		}
+		restarts += cs.RestartCount
+	}
+	return ready, total, restarts
+}
+
+// podStatus 和 kubectl get pods 的 STATUS 列类似, 优先展示容器的等待/退出原因
+func podStatus(pod *apiv1.Pod) string {
+	if pod.DeletionTimestamp != nil {
+		return "Terminating"
+	}
+
+	status := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		status = pod.Status.Reason
+	}
+
+	for _, cs := range pod.Status.InitContainerStatuses {
+		if cs.State.Terminated != nil && cs.State.Terminated.ExitCode == 0 {
+			continue
+		}
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" && cs.State.Waiting.Reason != "PodInitializing" {
+			return "Init:" + cs.State.Waiting.Reason
+		}
+		if cs.State.Terminated != nil {
+			return "Init:Error"
+		}
+		return "Init:" + status
+	}
+
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+			status = cs.State.Waiting.Reason
+		} else if cs.State.Terminated != nil && cs.State.Terminated.Reason != "" {
+			status = cs.State.Terminated.Reason
+		}
+	}
+	return status
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -0,0 +1,306 @@
+package clientset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+	"github.com/xlcbingo1999/example-client-go/stack"
+)
+
+const (
+	// deployment controller 写在 ReplicaSet 上的版本号注解
+	revisionAnnotation = "deployment.kubernetes.io/revision"
+	// Progressing condition 的 reason, 表示超过了 spec.progressDeadlineSeconds
+	reasonProgressDeadlineExceeded = "ProgressDeadlineExceeded"
+)
+
+// 这些容器等待原因通常意味着 rollout 不会自己恢复
+var podFailureReasons = map[string]bool{
+	"ErrImagePull":               true,
+	"ImagePullBackOff":           true,
+	"InvalidImageName":           true,
+	"CrashLoopBackOff":           true,
+	"CreateContainerConfigError": true,
+}
+
+// RolloutError 表示 deployment 没有成功完成滚动更新
+type RolloutError struct {
+	Deployment string
+	// ProgressDeadlineExceeded, ImagePullBackOff, CrashLoopBackOff 等, 单纯超时为 Timeout
+	Cause   string
+	Message string
+	Err     error
+}
+
+func (e *RolloutError) Error() string {
+	return fmt.Sprintf("rollout of deployment %s failed: %s: %s", e.Deployment, e.Cause, e.Message)
+}
+
+func (e *RolloutError) Unwrap() error { return e.Err }
+
+func (e *RolloutError) Reason() string { return e.Cause }
+
+// ExitCode 单纯超时返回 Timeout, 能确定失败原因的返回 RolloutFailed
+func (e *RolloutError) ExitCode() int {
+	if e.Cause == string(metav1.StatusReasonTimeout) {
+		return exitcode.Timeout
+	}
+	return exitcode.RolloutFailed
+}
+
+// waitForRollout 监听 deployment 及其 ReplicaSet/Pod, 直到新版本的副本全部更新并可用
+// 与 kubectl rollout status 的判断条件一致: observedGeneration, updatedReplicas, availableReplicas 都追上 spec
+func waitForRollout(ctx context.Context, clientset kubernetes.Interface, namespace, name string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get deployment %s/%s: %w", namespace, name, err)
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	log.Printf("Waiting up to %v for deployment %s rollout to finish\n", timeout, name)
+
+	byName := func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+	bySelector := func(options *metav1.ListOptions) {
+		options.LabelSelector = selector.String()
+	}
+
+	// 三个 informer 的任何变化都会触发一次重新计算
+	changed := make(chan struct{}, 1)
+	notify := cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { trigger(changed) },
+		UpdateFunc: func(oldObj, newObj interface{}) { trigger(changed) },
+		DeleteFunc: func(obj interface{}) { trigger(changed) },
+	}
+
+	deployInformer := newInformer(ctx, &appsv1.Deployment{}, byName,
+		func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+		},
+		func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().Deployments(namespace).Watch(ctx, opts)
+		})
+	rsInformer := newInformer(ctx, &appsv1.ReplicaSet{}, bySelector,
+		func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().ReplicaSets(namespace).List(ctx, opts)
+		},
+		func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().ReplicaSets(namespace).Watch(ctx, opts)
+		})
+	podInformer := newInformer(ctx, &apiv1.Pod{}, bySelector,
+		func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Pods(namespace).List(ctx, opts)
+		},
+		func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, opts)
+		})
+
+	for _, informer := range []cache.SharedIndexInformer{deployInformer, rsInformer, podInformer} {
+		if _, err := informer.AddEventHandler(notify); err != nil {
+			return err
+		}
+		go informer.Run(ctx.Done())
+	}
+	if !cache.WaitForCacheSync(ctx.Done(), deployInformer.HasSynced, rsInformer.HasSynced, podInformer.HasSynced) {
+		return &RolloutError{Deployment: name, Cause: string(metav1.StatusReasonTimeout), Message: "cache did not sync", Err: ctx.Err()}
+	}
+
+	lastStatus := ""
+	podProblems := map[string]string{}
+	for {
+		select {
+		case <-ctx.Done():
+			return rolloutTimeout(name, lastStatus, podProblems, ctx.Err())
+		case <-changed:
+		}
+
+		obj, exists, err := deployInformer.GetStore().GetByKey(namespace + "/" + name)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("deployment %s/%s was deleted while waiting for rollout", namespace, name)
+		}
+		deployment := obj.(*appsv1.Deployment)
+
+		// 旧的 condition 在新的 generation 被观察到之前没有参考意义
+		cond := progressingCondition(deployment)
+		if cond != nil && cond.Reason == reasonProgressDeadlineExceeded && deployment.Generation <= deployment.Status.ObservedGeneration {
+			return &RolloutError{Deployment: name, Cause: reasonProgressDeadlineExceeded, Message: cond.Message}
+		}
+
+		// 只保留当前仍然存在的问题, 已经恢复的 pod 不再报告
+		newRS := newestReplicaSet(deployment, rsInformer.GetStore().List())
+		failures := podFailures(newRS, podInformer.GetStore().List())
+		for key, reason := range failures {
+			if podProblems[key] != reason {
+				log.Printf("Pod %s: %s\n", key, reason)
+			}
+		}
+		podProblems = failures
+
+		status, done := rolloutStatus(deployment)
+		if status != lastStatus {
+			log.Println(status)
+			lastStatus = status
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+func trigger(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+type listFunc func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error)
+type watchFunc func(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+
+// newInformer 构建一个只关心 tweak 过滤后对象的 informer
+func newInformer(ctx context.Context, objType runtime.Object, tweak func(*metav1.ListOptions), list listFunc, watchFn watchFunc) cache.SharedIndexInformer {
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			tweak(&options)
+			return list(ctx, options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			tweak(&options)
+			return watchFn(ctx, options)
+		},
+	}
+	return cache.NewSharedIndexInformer(lw, objType, 0, cache.Indexers{})
+}
+
+// rolloutStatus 返回当前的进度描述, 以及 rollout 是否已经完成
+func rolloutStatus(d *appsv1.Deployment) (string, bool) {
+	replicas := stack.ReplicasOf(d.Spec.Replicas)
+	status := d.Status
+
+	switch {
+	case d.Generation > status.ObservedGeneration:
+		return fmt.Sprintf("Waiting for deployment %s spec update to be observed...", d.Name), false
+	case status.UpdatedReplicas < replicas:
+		return fmt.Sprintf("Waiting for deployment %s rollout to finish: %d out of %d new replicas have been updated...",
+			d.Name, status.UpdatedReplicas, replicas), false
+	case status.Replicas > status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %s rollout to finish: %d old replicas are pending termination...",
+			d.Name, status.Replicas-status.UpdatedReplicas), false
+	case status.AvailableReplicas < status.UpdatedReplicas:
+		return fmt.Sprintf("Waiting for deployment %s rollout to finish: %d of %d updated replicas are available...",
+			d.Name, status.AvailableReplicas, status.UpdatedReplicas), false
+	}
+	return fmt.Sprintf("Deployment %s successfully rolled out", d.Name), true
+}
+
+func progressingCondition(d *appsv1.Deployment) *appsv1.DeploymentCondition {
+	for i := range d.Status.Conditions {
+		if d.Status.Conditions[i].Type == appsv1.DeploymentProgressing {
+			return &d.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// ownedReplicaSets 返回 objs 中属于 deployment 的 ReplicaSet, 按版本号从小到大排序
+func ownedReplicaSets(d *appsv1.Deployment, objs []interface{}) []*appsv1.ReplicaSet {
+	var owned []*appsv1.ReplicaSet
+	for _, obj := range objs {
+		rs, ok := obj.(*appsv1.ReplicaSet)
+		if !ok {
+			continue
+		}
+		if ref := metav1.GetControllerOf(rs); ref != nil && ref.UID == d.UID {
+			owned = append(owned, rs)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool { return revisionOf(owned[i]) < revisionOf(owned[j]) })
+	return owned
+}
+
+// newestReplicaSet 返回 deployment 当前版本的 ReplicaSet, 还没有创建时返回 nil
+func newestReplicaSet(d *appsv1.Deployment, objs []interface{}) *appsv1.ReplicaSet {
+	owned := ownedReplicaSets(d, objs)
+	if len(owned) == 0 {
+		return nil
+	}
+	return owned[len(owned)-1]
+}
+
+func revisionOf(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+// podFailures 返回新 ReplicaSet 的 pod 中处于失败等待状态的容器, key 为 pod/container
+func podFailures(rs *appsv1.ReplicaSet, objs []interface{}) map[string]string {
+	failures := map[string]string{}
+	if rs == nil {
+		return failures
+	}
+	for _, obj := range objs {
+		pod, ok := obj.(*apiv1.Pod)
+		if !ok {
+			continue
+		}
+		if ref := metav1.GetControllerOf(pod); ref == nil || ref.UID != rs.UID {
+			continue
+		}
+
+		statuses := append(append([]apiv1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if cs.State.Waiting != nil && podFailureReasons[cs.State.Waiting.Reason] {
+				failures[pod.Name+"/"+cs.Name] = cs.State.Waiting.Reason + ": " + cs.State.Waiting.Message
+			}
+		}
+	}
+	return failures
+}
+
+// rolloutTimeout 在超时的时候构建错误, 如果观察到了 pod 失败原因, 优先报告失败原因
+func rolloutTimeout(name, lastStatus string, podProblems map[string]string, err error) error {
+	if !errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if len(podProblems) == 0 {
+		return &RolloutError{Deployment: name, Cause: string(metav1.StatusReasonTimeout), Message: lastStatus, Err: err}
+	}
+
+	keys := make([]string, 0, len(podProblems))
+	for key := range podProblems {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	problems := make([]string, 0, len(keys))
+	for _, key := range keys {
+		problems = append(problems, key+" "+podProblems[key])
+	}
+	cause := strings.SplitN(podProblems[keys[0]], ":", 2)[0]
+	return &RolloutError{Deployment: name, Cause: cause, Message: strings.Join(problems, "; "), Err: err}
+}
@@ -0,0 +1,289 @@
+package clientset
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"sigs.k8s.io/yaml"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/stack"
+)
+
+// Spec 描述 clientset_demo 部署的 namespace/deployment/service
+// 字段既可以通过命令行参数设置, 也可以通过 --spec-file 指定的 YAML/JSON 文件设置
+type Spec struct {
+	// 为空时使用 --namespace, 再为空时使用 NAMESPACE
+	Namespace      string `json:"namespace,omitempty"`
+	DeploymentName string `json:"deploymentName,omitempty"`
+	ServiceName    string `json:"serviceName,omitempty"`
+
+	ContainerName string            `json:"containerName,omitempty"`
+	Image         string            `json:"image,omitempty"`
+	Replicas      int32             `json:"replicas,omitempty"`
+	ContainerPort int32             `json:"containerPort,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+	// 资源名到数量的映射, 例如 cpu: 100m, memory: 128Mi
+	Requests map[string]string `json:"requests,omitempty"`
+	Limits   map[string]string `json:"limits,omitempty"`
+	// 同时作为 deployment selector, pod 模板标签和 service selector
+	Labels map[string]string `json:"labels,omitempty"`
+
+	ServiceType apiv1.ServiceType `json:"serviceType,omitempty"`
+	ServicePort int32             `json:"servicePort,omitempty"`
+	// 为 0 时由 apiserver 自动分配, ClusterIP 类型的 service 会忽略该字段
+	NodePort int32 `json:"nodePort,omitempty"`
+}
+
+var (
+	// Stack 由命令行参数填充, 使用 --spec-file 时只有显式设置的参数会覆盖文件中的字段
+	Stack = DefaultSpec()
+	// YAML 或 JSON 格式的 Spec 文件
+	SpecFile string
+	// FlagChanged 返回名为 name 的参数是否在命令行中显式设置, 由 cmd 设置
+	FlagChanged = func(name string) bool { return false }
+)
+
+// specFlags 把部署参数对应的字段从 src 复制到 dst
+var specFlags = map[string]func(dst, src *Spec){
+	"deployment-name": func(dst, src *Spec) { dst.DeploymentName = src.DeploymentName },
+	"service-name":    func(dst, src *Spec) { dst.ServiceName = src.ServiceName },
+	"container-name":  func(dst, src *Spec) { dst.ContainerName = src.ContainerName },
+	"image":           func(dst, src *Spec) { dst.Image = src.Image },
+	"replicas":        func(dst, src *Spec) { dst.Replicas = src.Replicas },
+	"container-port":  func(dst, src *Spec) { dst.ContainerPort = src.ContainerPort },
+	"env":             func(dst, src *Spec) { dst.Env = copyMap(src.Env) },
+	"requests":        func(dst, src *Spec) { dst.Requests = copyMap(src.Requests) },
+	"limits":          func(dst, src *Spec) { dst.Limits = copyMap(src.Limits) },
+	"labels":          func(dst, src *Spec) { dst.Labels = copyMap(src.Labels) },
+	"service-type":    func(dst, src *Spec) { dst.ServiceType = src.ServiceType },
+	"service-port":    func(dst, src *Spec) { dst.ServicePort = src.ServicePort },
+	"node-port":       func(dst, src *Spec) { dst.NodePort = src.NodePort },
+}
+
+// DefaultSpec 返回 clientset_demo 默认部署的 tomcat 服务
+func DefaultSpec() Spec {
+	return Spec{
+		DeploymentName: DEPLOYMENT_NAME,
+		ServiceName:    SERVICE_NAME,
+		ContainerName:  "tomcat",
+		Image:          "tomcat:8.0.18-jre8",
+		Replicas:       2,
+		ContainerPort:  8080,
+		Requests:       map[string]string{"cpu": "100m", "memory": "128Mi"},
+		Labels:         map[string]string{"app": "tomcat"},
+		ServiceType:    apiv1.ServiceTypeNodePort,
+		ServicePort:    8080,
+		NodePort:       30480,
+	}
+}
+
+// loadSpec 合并命令行参数和 spec 文件, 并在访问 apiserver 之前完成校验
+// 使用 spec 文件时, 文件中没有出现的字段使用默认值, 命令行中显式设置的参数优先于文件
+func loadSpec() (*Spec, error) {
+	spec := Stack.clone()
+	if SpecFile != "" {
+		file, err := readSpecFile(SpecFile)
+		if err != nil {
+			return nil, err
+		}
+		for name, copyFlag := range specFlags {
+			if FlagChanged(name) {
+				copyFlag(file, &Stack)
+			}
+		}
+		spec = *file
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = connection.NamespaceOr(NAMESPACE)
+	}
+
+	if errs := spec.Validate(); len(errs) > 0 {
+		return nil, apierrors.NewInvalid(schema.GroupKind{Kind: "Spec"}, spec.DeploymentName, errs)
+	}
+	return &spec, nil
+}
+
+// readSpecFile 读取 spec 文件, 文件中没有出现的字段使用默认值
+// map 类型的字段整体替换默认值而不是合并, 因此文件可以去掉默认的标签或资源请求
+func readSpecFile(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read spec file: %w", err)
+	}
+
+	defaults := DefaultSpec()
+	spec := defaults.clone()
+	spec.Env, spec.Requests, spec.Limits, spec.Labels = nil, nil, nil, nil
+	// sigs.k8s.io/yaml 同时支持 YAML 和 JSON
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse spec file %s: %w", path, err)
+	}
+	for _, m := range []struct{ got, def *map[string]string }{
+		{&spec.Env, &defaults.Env},
+		{&spec.Requests, &defaults.Requests},
+		{&spec.Limits, &defaults.Limits},
+		{&spec.Labels, &defaults.Labels},
+	} {
+		if *m.got == nil {
+			*m.got = *m.def
+		}
+	}
+	return &spec, nil
+}
+
+// clone 返回 s 的深拷贝
+func (s *Spec) clone() Spec {
+	copied := *s
+	copied.Env = copyMap(s.Env)
+	copied.Requests = copyMap(s.Requests)
+	copied.Limits = copyMap(s.Limits)
+	copied.Labels = copyMap(s.Labels)
+	return copied
+}
+
+// Validate 返回 Spec 中所有不合法的字段
+func (s *Spec) Validate() field.ErrorList {
+	var errs field.ErrorList
+
+	for _, msg := range validation.IsDNS1123Label(s.Namespace) {
+		errs = append(errs, field.Invalid(field.NewPath("namespace"), s.Namespace, msg))
+	}
+	for _, msg := range validation.IsDNS1123Subdomain(s.DeploymentName) {
+		errs = append(errs, field.Invalid(field.NewPath("deploymentName"), s.DeploymentName, msg))
+	}
+	for _, msg := range validation.IsDNS1035Label(s.ServiceName) {
+		errs = append(errs, field.Invalid(field.NewPath("serviceName"), s.ServiceName, msg))
+	}
+
+	for _, msg := range validation.IsDNS1123Label(s.ContainerName) {
+		errs = append(errs, field.Invalid(field.NewPath("containerName"), s.ContainerName, msg))
+	}
+	if s.Image == "" {
+		errs = append(errs, field.Required(field.NewPath("image"), ""))
+	}
+	if s.Replicas < 0 {
+		errs = append(errs, field.Invalid(field.NewPath("replicas"), s.Replicas, "must be greater than or equal to 0"))
+	}
+	errs = append(errs, validatePort(field.NewPath("containerPort"), s.ContainerPort)...)
+	errs = append(errs, validatePort(field.NewPath("servicePort"), s.ServicePort)...)
+
+	for name := range s.Env {
+		for _, msg := range validation.IsEnvVarName(name) {
+			errs = append(errs, field.Invalid(field.NewPath("env").Key(name), name, msg))
+		}
+	}
+	errs = append(errs, validateQuantities(field.NewPath("requests"), s.Requests)...)
+	errs = append(errs, validateQuantities(field.NewPath("limits"), s.Limits)...)
+
+	labelsPath := field.NewPath("labels")
+	if len(s.Labels) == 0 {
+		errs = append(errs, field.Required(labelsPath, "labels are used as the deployment and service selector"))
+	}
+	for k, v := range s.Labels {
+		for _, msg := range validation.IsQualifiedName(k) {
+			errs = append(errs, field.Invalid(labelsPath.Key(k), k, msg))
+		}
+		for _, msg := range validation.IsValidLabelValue(v) {
+			errs = append(errs, field.Invalid(labelsPath.Key(k), v, msg))
+		}
+	}
+
+	typePath := field.NewPath("serviceType")
+	switch s.ServiceType {
+	case apiv1.ServiceTypeClusterIP:
+		// ClusterIP 类型会忽略 nodePort
+	case apiv1.ServiceTypeNodePort, apiv1.ServiceTypeLoadBalancer:
+		if s.NodePort != 0 {
+			errs = append(errs, validatePort(field.NewPath("nodePort"), s.NodePort)...)
+		}
+	default:
+		errs = append(errs, field.NotSupported(typePath, s.ServiceType,
+			[]string{string(apiv1.ServiceTypeClusterIP), string(apiv1.ServiceTypeNodePort), string(apiv1.ServiceTypeLoadBalancer)}))
+	}
+	return errs
+}
+
+func validatePort(path *field.Path, port int32) field.ErrorList {
+	var errs field.ErrorList
+	for _, msg := range validation.IsValidPortNum(int(port)) {
+		errs = append(errs, field.Invalid(path, port, msg))
+	}
+	return errs
+}
+
+func validateQuantities(path *field.Path, quantities map[string]string) field.ErrorList {
+	var errs field.ErrorList
+	for name, value := range quantities {
+		if _, err := resource.ParseQuantity(value); err != nil {
+			errs = append(errs, field.Invalid(path.Key(name), value, err.Error()))
+		}
+	}
+	return errs
+}
+
+// app 返回 spec 描述的 deployment 和 service, 调用前需要先通过 Validate
+func (s *Spec) app() *stack.App {
+	return &stack.App{
+		Namespace:      s.Namespace,
+		DeploymentName: s.DeploymentName,
+		ServiceName:    s.ServiceName,
+		Labels:         s.Labels,
+		ContainerName:  s.ContainerName,
+		Image:          s.Image,
+		Replicas:       s.Replicas,
+		ContainerPort:  s.ContainerPort,
+		Env:            s.envVars(),
+		Resources:      s.resources(),
+		ServiceType:    s.ServiceType,
+		ServicePort:    s.ServicePort,
+		NodePort:       s.NodePort,
+	}
+}
+
+// envVars 把 env 转换成按名字排序的 EnvVar, 保证每次生成的 pod 模板一致
+func (s *Spec) envVars() []apiv1.EnvVar {
+	var env []apiv1.EnvVar
+	for name, value := range s.Env {
+		env = append(env, apiv1.EnvVar{Name: name, Value: value})
+	}
+	sort.Slice(env, func(i, j int) bool { return env[i].Name < env[j].Name })
+	return env
+}
+
+// resources 把 requests/limits 转换成 ResourceRequirements, 调用前需要先通过 Validate
+func (s *Spec) resources() apiv1.ResourceRequirements {
+	toList := func(quantities map[string]string) apiv1.ResourceList {
+		if len(quantities) == 0 {
+			return nil
+		}
+		list := apiv1.ResourceList{}
+		for name, value := range quantities {
+			list[apiv1.ResourceName(name)] = resource.MustParse(value)
+		}
+		return list
+	}
+	return apiv1.ResourceRequirements{
+		Requests: toList(s.Requests),
+		Limits:   toList(s.Limits),
+	}
+}
+
+// copyMap 返回 m 的副本, 避免多个对象共享同一个 map
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+	return copied
+}
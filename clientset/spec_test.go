@@ -0,0 +1,93 @@
+package clientset
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useSpecFile 把 data 写入 spec 文件, 并把 changed 中的参数视为显式设置, 测试结束后恢复
+func useSpecFile(t *testing.T, data string, changed ...string) {
+	t.Helper()
+	savedStack, savedFile, savedChanged := Stack.clone(), SpecFile, FlagChanged
+	t.Cleanup(func() {
+		Stack, SpecFile, FlagChanged = savedStack, savedFile, savedChanged
+	})
+
+	SpecFile = filepath.Join(t.TempDir(), "spec.yaml")
+	if err := os.WriteFile(SpecFile, []byte(data), 0o600); err != nil {
+		t.Fatalf("write spec file: %v", err)
+	}
+	FlagChanged = func(name string) bool {
+		for _, c := range changed {
+			if c == name {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestLoadSpecFileReplacesMaps(t *testing.T) {
+	useSpecFile(t, `
+image: nginx:1.25
+labels:
+  app: web
+  tier: frontend
+requests:
+  cpu: 50m
+`)
+
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatalf("loadSpec() error = %v", err)
+	}
+	if want := map[string]string{"app": "web", "tier": "frontend"}; !maps.Equal(spec.Labels, want) {
+		t.Errorf("labels = %v, want %v", spec.Labels, want)
+	}
+	// 默认的 memory 请求被文件去掉
+	if want := map[string]string{"cpu": "50m"}; !maps.Equal(spec.Requests, want) {
+		t.Errorf("requests = %v, want %v", spec.Requests, want)
+	}
+	// 文件中没有出现的字段使用默认值
+	if defaults := DefaultSpec(); spec.Replicas != defaults.Replicas || spec.ContainerName != defaults.ContainerName {
+		t.Errorf("replicas, containerName = %d, %s, want the defaults", spec.Replicas, spec.ContainerName)
+	}
+}
+
+func TestLoadSpecExplicitFlagsOverrideFile(t *testing.T) {
+	useSpecFile(t, `
+image: nginx:1.25
+replicas: 0
+servicePort: 80
+labels:
+  app: web
+`, "image", "labels")
+	Stack.Image = "nginx:1.26"
+	Stack.Labels = map[string]string{"app": "canary"}
+	// 没有显式设置的参数不覆盖文件
+	Stack.ServicePort = 9090
+
+	spec, err := loadSpec()
+	if err != nil {
+		t.Fatalf("loadSpec() error = %v", err)
+	}
+	if spec.Image != "nginx:1.26" {
+		t.Errorf("image = %s, want the flag value nginx:1.26", spec.Image)
+	}
+	if want := map[string]string{"app": "canary"}; !maps.Equal(spec.Labels, want) {
+		t.Errorf("labels = %v, want %v", spec.Labels, want)
+	}
+	if spec.ServicePort != 80 || spec.Replicas != 0 {
+		t.Errorf("servicePort, replicas = %d, %d, want 80, 0 from the file", spec.ServicePort, spec.Replicas)
+	}
+}
+
+func TestLoadSpecFileRejectsUnknownFields(t *testing.T) {
+	useSpecFile(t, "image: nginx:1.25\nreplica: 3\n")
+
+	if _, err := loadSpec(); err == nil {
+		t.Error("loadSpec() error = nil for a misspelled field")
+	}
+}
@@ -0,0 +1,158 @@
+package clientset
+
+import (
+	"fmt"
+	"log"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	"github.com/xlcbingo1999/example-client-go/stack"
+)
+
+// apiserver 默认的 --service-node-port-range
+const (
+	minNodePort = 30000
+	maxNodePort = 32767
+)
+
+// 这些端口名约定俗成地表示基于 TCP 的应用层协议
+var tcpPortNames = map[string]bool{"http": true, "https": true, "grpc": true, "h2c": true}
+
+// Force 为 true 时, 校验失败只打印告警, 仍然提交对象
+var Force bool
+
+// checkStack 在提交之前校验 spec 生成的 deployment 和 service, 一次性报告所有问题
+func checkStack(spec *Spec) error {
+	errs := validateStack(stack.NewDeployment(spec.app()), stack.NewService(spec.app()))
+	if len(errs) == 0 {
+		return nil
+	}
+
+	if Force {
+		for _, err := range errs {
+			log.Printf("Warning: %v\n", err)
+		}
+		log.Printf("Submitting %d invalid field(s) because --force is set\n", len(errs))
+		return nil
+	}
+	return fmt.Errorf("refusing to submit, use --force to override: %w",
+		apierrors.NewInvalid(schema.GroupKind{Kind: "Stack"}, spec.DeploymentName, errs))
+}
+
+// validateStack 检查 apiserver 本身不会拒绝, 但会导致服务不可用的配置问题
+func validateStack(deployment *appsv1.Deployment, service *apiv1.Service) field.ErrorList {
+	var errs field.ErrorList
+	errs = append(errs, validateDeployment(field.NewPath("deployment"), deployment)...)
+	errs = append(errs, validateService(field.NewPath("service"), service, &deployment.Spec.Template)...)
+	return errs
+}
+
+func validateDeployment(path *field.Path, deployment *appsv1.Deployment) field.ErrorList {
+	var errs field.ErrorList
+	specPath := path.Child("spec")
+	template := &deployment.Spec.Template
+
+	// selector 必须能选中 pod 模板, 否则 deployment 无法管理自己创建的 pod
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		errs = append(errs, field.Invalid(specPath.Child("selector"), deployment.Spec.Selector, err.Error()))
+	} else if selector.Empty() || !selector.Matches(labels.Set(template.Labels)) {
+		errs = append(errs, field.Invalid(specPath.Child("selector"), metav1.FormatLabelSelector(deployment.Spec.Selector),
+			fmt.Sprintf("does not match template labels %v", labels.Set(template.Labels))))
+	}
+
+	containersPath := specPath.Child("template", "spec", "containers")
+	portNames := map[string]bool{}
+	for i, c := range template.Spec.Containers {
+		containerPath := containersPath.Index(i)
+
+		for j, port := range c.Ports {
+			portPath := containerPath.Child("ports").Index(j)
+			if port.Name != "" {
+				if portNames[port.Name] {
+					errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+				}
+				portNames[port.Name] = true
+			}
+			if tcpPortNames[port.Name] && stack.ProtocolOf(port.Protocol) != apiv1.ProtocolTCP {
+				errs = append(errs, field.Invalid(portPath.Child("protocol"), port.Protocol,
+					fmt.Sprintf("port %q carries a TCP based protocol", port.Name)))
+			}
+		}
+
+		requestsPath := containerPath.Child("resources", "requests")
+		for _, name := range []apiv1.ResourceName{apiv1.ResourceCPU, apiv1.ResourceMemory} {
+			if _, ok := c.Resources.Requests[name]; !ok {
+				errs = append(errs, field.Required(requestsPath.Key(string(name)), "resource requests are needed for scheduling"))
+			}
+		}
+	}
+	return errs
+}
+
+func validateService(path *field.Path, service *apiv1.Service, template *apiv1.PodTemplateSpec) field.ErrorList {
+	var errs field.ErrorList
+	specPath := path.Child("spec")
+
+	// service 的 selector 必须是 pod 标签的子集, 否则没有 endpoint
+	selector := labels.SelectorFromSet(service.Spec.Selector)
+	if len(service.Spec.Selector) == 0 || !selector.Matches(labels.Set(template.Labels)) {
+		errs = append(errs, field.Invalid(specPath.Child("selector"), service.Spec.Selector,
+			fmt.Sprintf("does not select pod template labels %v", labels.Set(template.Labels))))
+	}
+
+	portNames := map[string]bool{}
+	for i, port := range service.Spec.Ports {
+		portPath := specPath.Child("ports").Index(i)
+
+		if port.Name == "" && len(service.Spec.Ports) > 1 {
+			errs = append(errs, field.Required(portPath.Child("name"), "required when the service has multiple ports"))
+		}
+		if portNames[port.Name] {
+			errs = append(errs, field.Duplicate(portPath.Child("name"), port.Name))
+		}
+		portNames[port.Name] = true
+
+		if port.NodePort != 0 && (port.NodePort < minNodePort || port.NodePort > maxNodePort) {
+			errs = append(errs, field.Invalid(portPath.Child("nodePort"), port.NodePort,
+				fmt.Sprintf("must be in the range %d-%d", minNodePort, maxNodePort)))
+		}
+
+		// targetPort 必须指向 pod 中协议一致的容器端口
+		target, ok := findContainerPort(template, port)
+		if !ok {
+			targetPort := stack.TargetPortOf(port)
+			errs = append(errs, field.Invalid(portPath.Child("targetPort"), targetPort.String(),
+				"does not match any container port"))
+			continue
+		}
+		if stack.ProtocolOf(port.Protocol) != stack.ProtocolOf(target.Protocol) {
+			errs = append(errs, field.Invalid(portPath.Child("protocol"), stack.ProtocolOf(port.Protocol),
+				fmt.Sprintf("container port %d uses %s", target.ContainerPort, stack.ProtocolOf(target.Protocol))))
+		}
+	}
+	return errs
+}
+
+// findContainerPort 找到 service 端口转发到的容器端口
+func findContainerPort(template *apiv1.PodTemplateSpec, port apiv1.ServicePort) (apiv1.ContainerPort, bool) {
+	target := stack.TargetPortOf(port)
+	for _, c := range template.Spec.Containers {
+		for _, p := range c.Ports {
+			if target.Type == intstr.String && p.Name == target.StrVal {
+				return p, true
+			}
+			if target.Type == intstr.Int && p.ContainerPort == target.IntVal {
+				return p, true
+			}
+		}
+	}
+	return apiv1.ContainerPort{}, false
+}
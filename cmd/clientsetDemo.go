@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"log"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xlcbingo1999/example-client-go/clientset"
@@ -10,18 +10,44 @@ import (
 var clientsetDemoCmd = &cobra.Command{
 	Use:   "clientset_demo",
 	Short: "Run clientset_demo",
-	Run: func(cmd *cobra.Command, args []string) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Fatalln("Recover err", err)
-			}
-		}()
-
-		clientset.RunClientSet()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		clientset.FlagChanged = cmd.Flags().Changed
+		return clientset.RunClientSet()
 	},
 }
 
 func init() {
-	clientsetDemoCmd.Flags().StringVarP(&clientset.Operate, "operate", "", "create", "operate type : create or clean or list")
+	flags := clientsetDemoCmd.Flags()
+	flags.StringVarP(&clientset.Operate, "operate", "", "create", "operate type : create, apply, clean, list, scale, restart, history or rollback")
+	flags.BoolVarP(&clientset.Wait, "wait", "", false, "wait for the operation to settle: the deployment rollout after create/apply, the namespace to be gone after clean")
+	flags.DurationVarP(&clientset.Timeout, "timeout", "", 5*time.Minute, "how long --wait, scale, restart and rollback wait before giving up")
+	flags.StringVarP(&clientset.Propagation, "propagation", "", "background", "deletion propagation policy used by clean: background, foreground or orphan")
+	flags.Int64VarP(&clientset.GracePeriod, "grace-period", "", -1, "seconds given to objects to terminate gracefully during clean, negative uses the object default")
+
+	// list 模式的参数
+	flags.StringVarP(&clientset.LabelSelector, "selector", "l", "", "label selector used by list, e.g. app=tomcat")
+	flags.StringVarP(&clientset.FieldSelector, "field-selector", "", "", "field selector used by list, e.g. status.phase=Running")
+	flags.BoolVarP(&clientset.AllNamespaces, "all-namespaces", "A", false, "list pods in all namespaces")
+	flags.Int64VarP(&clientset.PageSize, "limit", "", 500, "number of pods fetched per page by list, 0 fetches all at once")
+	flags.StringVarP(&clientset.Output, "output", "o", "table", "output format of list: table, wide, json, yaml or name")
+	flags.Int64VarP(&clientset.ToRevision, "to-revision", "", 0, "revision used by rollback, 0 means the previous revision")
+
+	// 部署的资源, 显式设置的参数优先于 --spec-file 中的字段
+	stack := &clientset.Stack
+	flags.BoolVarP(&clientset.Force, "force", "", false, "submit the stack even if pre-submission validation fails")
+	flags.StringVarP(&clientset.SpecFile, "spec-file", "f", "", "YAML or JSON file describing the deployed stack, fields it omits use the defaults and flags set explicitly take precedence")
+	flags.StringVarP(&stack.DeploymentName, "deployment-name", "", stack.DeploymentName, "name of the deployment")
+	flags.StringVarP(&stack.ServiceName, "service-name", "", stack.ServiceName, "name of the service")
+	flags.StringVarP(&stack.ContainerName, "container-name", "", stack.ContainerName, "name of the container")
+	flags.StringVarP(&stack.Image, "image", "", stack.Image, "container image")
+	flags.Int32VarP(&stack.Replicas, "replicas", "", stack.Replicas, "number of replicas, also the target of scale")
+	flags.Int32VarP(&stack.ContainerPort, "container-port", "", stack.ContainerPort, "port the container listens on")
+	flags.StringToStringVarP(&stack.Env, "env", "", stack.Env, "environment variables of the container, e.g. KEY=VALUE")
+	flags.StringToStringVarP(&stack.Requests, "requests", "", stack.Requests, "resource requests of the container, e.g. cpu=100m,memory=128Mi")
+	flags.StringToStringVarP(&stack.Limits, "limits", "", stack.Limits, "resource limits of the container, e.g. cpu=500m,memory=512Mi")
+	flags.StringToStringVarP(&stack.Labels, "labels", "", stack.Labels, "labels used as the deployment and service selector")
+	flags.StringVarP((*string)(&stack.ServiceType), "service-type", "", string(stack.ServiceType), "service type: ClusterIP, NodePort or LoadBalancer")
+	flags.Int32VarP(&stack.ServicePort, "service-port", "", stack.ServicePort, "port exposed by the service")
+	flags.Int32VarP(&stack.NodePort, "node-port", "", stack.NodePort, "node port of the service, 0 lets the apiserver allocate one")
 	rootCmd.AddCommand(clientsetDemoCmd)
 }
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/spf13/cobra"
 	"github.com/xlcbingo1999/example-client-go/conflictaction"
 )
@@ -10,14 +8,8 @@ import (
 var conflictactionDemoCmd = &cobra.Command{
 	Use:   "conflictaction_demo",
 	Short: "Run conflictaction_demo",
-	Run: func(cmd *cobra.Command, args []string) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Fatalln("Recover err", err)
-			}
-		}()
-
-		conflictaction.RunConflictAction()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return conflictaction.RunConflictAction()
 	},
 }
 
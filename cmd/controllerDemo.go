@@ -1,7 +1,7 @@
 package cmd
 
 import (
-	"log"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/xlcbingo1999/example-client-go/controller"
@@ -10,17 +10,45 @@ import (
 var controllerDemoCmd = &cobra.Command{
 	Use:   "controller_demo",
 	Short: "Run controller_demo",
-	Run: func(cmd *cobra.Command, args []string) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Fatalln("Recover err", err)
-			}
-		}()
-
-		controller.RunController()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return controller.RunController()
 	},
 }
 
 func init() {
+	flags := controllerDemoCmd.Flags()
+	flags.StringVarP(&controller.Resource, "resource", "", controller.ResourcePods, "primary resource to reconcile: pods, or deployments together with their replicasets, pods and services")
+	flags.BoolVarP(&controller.AllNamespaces, "all-namespaces", "A", false, "watch all namespaces instead of --namespace")
+	flags.StringVarP(&controller.LabelSelector, "selector", "l", "", "only watch pods matching this label selector, e.g. app=tomcat")
+	flags.StringVarP(&controller.FieldSelector, "field-selector", "", "", "only watch pods matching this field selector, e.g. spec.nodeName=node1")
+	flags.StringVarP(&controller.FinalizerName, "finalizer", "", "", "finalizer added to every processed object and removed after cleanup on deletion, e.g. example.com/cleanup; objects keep it if the controller is stopped")
+	flags.DurationVarP(&controller.ShutdownTimeout, "shutdown-timeout", "", 30*time.Second, "how long to wait for in-flight syncs to finish after SIGINT/SIGTERM")
+	flags.StringVarP(&controller.MetricsAddr, "metrics-addr", "", "", "address serving Prometheus metrics on /metrics, e.g. :8080, empty disables it")
+	flags.StringVarP(&controller.ProbeAddr, "probe-addr", "", "", "address serving /healthz, /readyz and /debug, empty disables it")
+
+	// 失败重试参数
+	flags.IntVarP(&controller.MaxRetries, "max-retries", "", 5, "how many times a failing key is retried before it is dropped into the dead-letter record")
+	flags.StringVarP(&controller.RateLimiter.Kind, "rate-limiter", "", controller.RateLimiterMaxOf, "workqueue rate limiter: exponential, bucket or max-of")
+	flags.DurationVarP(&controller.RateLimiter.BaseDelay, "retry-base-delay", "", 5*time.Millisecond, "initial per-key backoff of the exponential rate limiter")
+	flags.DurationVarP(&controller.RateLimiter.MaxDelay, "retry-max-delay", "", 1000*time.Second, "maximum per-key backoff of the exponential rate limiter")
+	flags.Float64VarP(&controller.RateLimiter.QPS, "retry-qps", "", 10, "overall retry rate of the bucket rate limiter")
+	flags.IntVarP(&controller.RateLimiter.Burst, "retry-burst", "", 100, "burst size of the bucket rate limiter")
+	flags.IntVarP(&controller.DeadLetterLimit, "dead-letter-limit", "", 100, "how many dropped keys are kept for /debug/<pod|deployment>/dead-letters, 0 disables the record")
+
+	// 事件参数
+	flags.BoolVarP(&controller.RecordEvents, "record-events", "", false, "emit Kubernetes events on the objects the controller processes, needs RBAC to create events")
+	flags.StringVarP(&controller.Component, "component", "", "controller-demo", "component name recorded as the source of emitted events")
+	flags.Float32VarP(&controller.EventLimit.QPS, "event-qps", "", 0, "events per second allowed per object, 0 uses the client-go default of one every 5 minutes")
+	flags.IntVarP(&controller.EventLimit.Burst, "event-burst", "", 0, "events per object allowed before rate limiting kicks in, 0 uses the client-go default of 25")
+
+	// 选主参数
+	flags.BoolVarP(&controller.LeaderElect, "leader-elect", "", false, "run leader election so that only one replica processes pods")
+	flags.StringVarP(&controller.LeaseName, "lease-name", "", "controller-demo", "name of the coordination.k8s.io Lease used for leader election")
+	flags.StringVarP(&controller.LeaseNamespace, "lease-namespace", "", "default", "namespace of the Lease used for leader election")
+	flags.DurationVarP(&controller.LeaseDuration, "lease-duration", "", 15*time.Second, "how long non-leaders wait before trying to take over an unrenewed lease")
+	flags.DurationVarP(&controller.RenewDeadline, "renew-deadline", "", 10*time.Second, "how long the leader keeps retrying to renew before giving up leadership")
+	flags.DurationVarP(&controller.RetryPeriod, "retry-period", "", 2*time.Second, "interval between attempts to acquire or renew the lease")
+	flags.StringVarP(&controller.LeaderIdentity, "leader-identity", "", "", "identity recorded in the lease, defaults to the hostname with a random suffix")
+
 	rootCmd.AddCommand(controllerDemoCmd)
 }
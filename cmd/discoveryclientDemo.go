@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/spf13/cobra"
 	"github.com/xlcbingo1999/example-client-go/discoveryclient"
 )
@@ -10,14 +8,8 @@ import (
 var discoveryclientDemoCmd = &cobra.Command{
 	Use:   "discoveryclient_demo",
 	Short: "Run discoveryclient_demo",
-	Run: func(cmd *cobra.Command, args []string) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Fatalln("Recover err", err)
-			}
-		}()
-
-		discoveryclient.RunDiscoveryClient()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return discoveryclient.RunDiscoveryClient()
 	},
 }
 
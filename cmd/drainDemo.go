@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xlcbingo1999/example-client-go/drain"
+)
+
+var drainCmd = &cobra.Command{
+	Use:   "drain NODE",
+	Short: "Cordon a node and evict its pods through the eviction API",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return drain.RunDrain(args[0])
+	},
+}
+
+var uncordonCmd = &cobra.Command{
+	Use:   "uncordon NODE",
+	Short: "Mark a node as schedulable again",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return drain.RunUncordon(args[0])
+	},
+}
+
+func init() {
+	flags := drainCmd.Flags()
+	flags.BoolVarP(&drain.DeleteEmptyDirData, "delete-emptydir-data", "", false, "evict pods using emptyDir volumes, their local data is lost")
+	flags.Int64VarP(&drain.GracePeriod, "grace-period", "", -1, "seconds given to each pod to terminate gracefully, negative uses the pod default")
+	flags.DurationVarP(&drain.Timeout, "timeout", "", 0, "how long to wait for the drain to finish, 0 waits forever")
+
+	rootCmd.AddCommand(drainCmd)
+	rootCmd.AddCommand(uncordonCmd)
+}
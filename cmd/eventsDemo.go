@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xlcbingo1999/example-client-go/events"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "List and watch events of the objects managed by the demos",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return events.RunEvents()
+	},
+}
+
+func init() {
+	flags := eventsCmd.Flags()
+	flags.StringVarP(&events.Kind, "for-kind", "", "", "only show events of this involved object kind, e.g. Deployment")
+	flags.StringVarP(&events.Name, "for-name", "", "", "only show events of the involved object with this name")
+	flags.StringVarP(&events.Type, "type", "", "", "only show events of this type: Normal or Warning")
+	flags.StringVarP(&events.Reason, "reason", "", "", "only show events with this reason, e.g. FailedScheduling")
+	flags.BoolVarP(&events.AllNamespaces, "all-namespaces", "A", false, "show events in all namespaces")
+	flags.BoolVarP(&events.Watch, "watch", "w", false, "keep watching for new events after listing")
+	flags.StringVarP(&events.Output, "output", "o", "table", "output format: table or json")
+
+	rootCmd.AddCommand(eventsCmd)
+}
@@ -1,8 +1,6 @@
 package cmd
 
 import (
-	"log"
-
 	"github.com/spf13/cobra"
 	"github.com/xlcbingo1999/example-client-go/informer"
 )
@@ -10,17 +8,12 @@ import (
 var informerDemoCmd = &cobra.Command{
 	Use:   "informer_demo",
 	Short: "Run informer_demo",
-	Run: func(cmd *cobra.Command, args []string) {
-		defer func() {
-			if err := recover(); err != nil {
-				log.Fatalln("Recover err", err)
-			}
-		}()
-
-		informer.RunInformer()
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return informer.RunInformer()
 	},
 }
 
 func init() {
+	informerDemoCmd.Flags().StringVarP(&informer.ProbeAddr, "probe-addr", "", "", "address serving /healthz, /readyz and /debug, empty disables it")
 	rootCmd.AddCommand(informerDemoCmd)
 }
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/xlcbingo1999/example-client-go/clientset"
+	"github.com/xlcbingo1999/example-client-go/podlogs"
+)
+
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Stream logs of every container of the pods behind a deployment or label selector",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return podlogs.RunPodLogs()
+	},
+}
+
+func init() {
+	flags := logsCmd.Flags()
+	flags.StringVarP(&podlogs.Deployment, "deployment", "d", clientset.DEPLOYMENT_NAME, "deployment whose selector is used to find pods")
+	flags.StringVarP(&podlogs.Selector, "selector", "l", "", "label selector used to find pods, overrides --deployment")
+	flags.StringVarP(&podlogs.Container, "container", "c", "", "only stream this container, empty streams all containers")
+	flags.BoolVarP(&podlogs.Follow, "follow", "f", false, "keep streaming and pick up new pods and restarted containers")
+	flags.BoolVarP(&podlogs.Previous, "previous", "p", false, "stream logs of the previous terminated container instance")
+	flags.BoolVarP(&podlogs.Timestamps, "timestamps", "", false, "include timestamps on each line")
+	flags.DurationVarP(&podlogs.Since, "since", "", 0, "only return logs newer than a relative duration like 5s, 2m or 3h")
+	flags.Int64VarP(&podlogs.Tail, "tail", "", -1, "lines of recent log to show per container, negative shows all")
+
+	rootCmd.AddCommand(logsCmd)
+}
@@ -1,20 +1,48 @@
 package cmd
 
 import (
-	"log"
 	"os"
 
 	"github.com/spf13/cobra"
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/exitcode"
 )
 
+// 错误摘要的输出格式: text 或 json
+var errorFormat string
+
 var rootCmd = &cobra.Command{
 	Use:   "test_go_basic",
 	Short: "run test_go_basic project",
+	// 错误由 Execute 统一输出, 避免 cobra 再打印一次 usage
+	SilenceErrors: true,
+	SilenceUsage:  true,
 }
 
+// Execute 执行命令, 出错时输出错误摘要并以对应的退出码退出
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		log.Fatalln("fail to start cobra execute: ", err)
-		os.Exit(1)
+		os.Exit(exitcode.Report(os.Stderr, err, errorFormat))
 	}
 }
+
+func init() {
+	rootCmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return &exitcode.UsageError{Err: err}
+	})
+
+	flags := rootCmd.PersistentFlags()
+	flags.StringVarP(&connection.Kubeconfig, "kubeconfig", "", "", "path to the kubeconfig file, defaults to $KUBECONFIG (merged) or ~/.kube/config")
+	flags.StringVarP(&connection.Context, "context", "", "", "name of the kubeconfig context to use")
+	flags.StringVarP(&connection.Namespace, "namespace", "n", "", "namespace to operate in, defaults to the namespace of each demo")
+	flags.BoolVarP(&connection.InCluster, "in-cluster", "", false, "use the in-cluster service account config instead of kubeconfig")
+
+	flags.Float32VarP(&connection.QPS, "qps", "", 50, "maximum queries per second to the apiserver, 0 keeps the client-go default")
+	flags.IntVarP(&connection.Burst, "burst", "", 100, "maximum burst of queries to the apiserver, 0 keeps the client-go default")
+	flags.DurationVarP(&connection.Timeout, "request-timeout", "", 0, "timeout of a single apiserver request, 0 means no timeout")
+	flags.StringVarP(&connection.UserAgent, "user-agent", "", "", "user agent sent to the apiserver")
+	flags.StringVarP(&connection.Impersonate, "as", "", "", "username to impersonate for the operation")
+	flags.StringSliceVarP(&connection.ImpersonateGroups, "as-group", "", nil, "group to impersonate for the operation, can be repeated")
+	flags.BoolVarP(&connection.Debug, "debug", "", false, "log the effective client config with secrets redacted")
+	flags.StringVarP(&errorFormat, "error-format", "", "text", "format of the error summary written to stderr: text or json")
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/xlcbingo1999/example-client-go/webapp"
+)
+
+var webappDemoCmd = &cobra.Command{
+	Use:   "webapp_demo",
+	Short: "Run webapp_demo, a controller reconciling WebApp custom resources into a Deployment and a Service",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return webapp.RunWebApp()
+	},
+}
+
+func init() {
+	flags := webappDemoCmd.Flags()
+	flags.BoolVarP(&webapp.InstallCRD, "install-crd", "", false, "apply the WebApp CustomResourceDefinition and wait for it to be established before starting")
+	flags.BoolVarP(&webapp.AllNamespaces, "all-namespaces", "A", false, "watch all namespaces instead of --namespace")
+	flags.IntVarP(&webapp.Workers, "workers", "", 2, "number of webapps reconciled concurrently")
+	flags.DurationVarP(&webapp.ShutdownTimeout, "shutdown-timeout", "", 30*time.Second, "how long to wait for in-flight syncs to finish after SIGINT/SIGTERM")
+	flags.StringVarP(&webapp.MetricsAddr, "metrics-addr", "", "", "address serving Prometheus metrics on /metrics, empty disables it")
+	flags.StringVarP(&webapp.ProbeAddr, "probe-addr", "", "", "address serving /healthz, /readyz and /debug, empty disables it")
+
+	rootCmd.AddCommand(webappDemoCmd)
+}
@@ -5,14 +5,12 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/xlcbingo1999/example-client-go/connection"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/client-go/util/retry"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -28,16 +26,17 @@ const (
 	LABEL_CUSTOMIZE string = "biz-version"
 )
 
-var (
-	Kubeconfig string
-)
+// namespace 返回 deployment 所在的 namespace, 默认为 default
+func namespace() string {
+	return connection.NamespaceOr(apiv1.NamespaceDefault)
+}
 
 func int32Ptr(i int32) *int32 {
 	return &i
 }
 
 func create(clientset *kubernetes.Clientset) error {
-	deploymentsClient := clientset.AppsV1().Deployments(apiv1.NamespaceDefault)
+	deploymentsClient := clientset.AppsV1().Deployments(namespace())
 
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -91,7 +90,7 @@ func create(clientset *kubernetes.Clientset) error {
 func delete(clientset *kubernetes.Clientset, name string) error {
 	deletePolicy := metav1.DeletePropagationBackground
 
-	err := clientset.AppsV1().Deployments(apiv1.NamespaceDefault).Delete(context.TODO(), name, metav1.DeleteOptions{PropagationPolicy: &deletePolicy})
+	err := clientset.AppsV1().Deployments(namespace()).Delete(context.TODO(), name, metav1.DeleteOptions{PropagationPolicy: &deletePolicy})
 
 	if err != nil {
 		return err
@@ -102,7 +101,7 @@ func delete(clientset *kubernetes.Clientset, name string) error {
 }
 
 func get(clientset *kubernetes.Clientset, name string) (*appsv1.Deployment, error) {
-	deployment, err := clientset.AppsV1().Deployments(apiv1.NamespaceDefault).Get(context.TODO(), name, metav1.GetOptions{})
+	deployment, err := clientset.AppsV1().Deployments(namespace()).Get(context.TODO(), name, metav1.GetOptions{})
 
 	if err != nil {
 		return nil, err
@@ -111,7 +110,7 @@ func get(clientset *kubernetes.Clientset, name string) (*appsv1.Deployment, erro
 }
 
 func updateByGetAndUpdate(clientset *kubernetes.Clientset, name string) error {
-	deployment, err := clientset.AppsV1().Deployments(apiv1.NamespaceDefault).Get(context.TODO(), name, metav1.GetOptions{})
+	deployment, err := clientset.AppsV1().Deployments(namespace()).Get(context.TODO(), name, metav1.GetOptions{})
 
 	if err != nil {
 		return err
@@ -134,7 +133,7 @@ func updateByGetAndUpdate(clientset *kubernetes.Clientset, name string) error {
 	// 将int型的label加一，再转为字符串
 	deployment.Labels[LABEL_CUSTOMIZE] = strconv.Itoa(val + 1)
 
-	_, err = clientset.AppsV1().Deployments(apiv1.NamespaceDefault).Update(context.TODO(), deployment, metav1.UpdateOptions{})
+	_, err = clientset.AppsV1().Deployments(namespace()).Update(context.TODO(), deployment, metav1.UpdateOptions{})
 	return err
 }
 
@@ -194,27 +193,17 @@ func (conflict Confilct) DoAction(clientset *kubernetes.Clientset) error {
 	return nil
 }
 
-func RunConflictAction() {
-	if home := homedir.HomeDir(); home != "" {
-		Kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-
-	config, err := clientcmd.BuildConfigFromFlags("", Kubeconfig)
-	if err != nil {
-		panic(err.Error())
-	}
-
+func RunConflictAction() error {
 	// 创建 ClientSet 实例
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := connection.NewClientSet()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
 	conflict := &Confilct{}
-	err = conflict.DoAction(clientset)
-	if err != nil {
-		fmt.Printf("err: %v\n", err)
-	} else {
-		fmt.Println("执行完成")
+	if err := conflict.DoAction(clientset); err != nil {
+		return err
 	}
+	fmt.Println("执行完成")
+	return nil
 }
@@ -0,0 +1,142 @@
+package connection
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// 这些变量由 rootCmd 的 persistent flags 填充, 所有 *_demo 命令共用
+var (
+	// kubeconfig 文件路径, 为空时按 KUBECONFIG 环境变量(多个文件会合并)和 ~/.kube/config 的顺序加载
+	Kubeconfig string
+	// 使用 kubeconfig 中的哪个 context, 为空时使用 current-context
+	Context string
+	// 操作的 namespace, 为空时使用各个命令自己的默认值
+	Namespace string
+	// 强制使用 inCluster 模式(需要在 Pod 内运行, 并配置对应的 RBAC 权限)
+	InCluster bool
+
+	// 客户端限流参数, client-go 默认的 5 QPS / 10 burst 在繁忙的集群上会导致明显的排队
+	QPS   float32
+	Burst int
+	// 单个请求的超时时间, 0 表示不限制
+	Timeout time.Duration
+	// 自定义 User-Agent, 为空时使用 client-go 的默认值
+	UserAgent string
+	// 以指定的用户/用户组身份访问 apiserver (--as/--as-group)
+	Impersonate       string
+	ImpersonateGroups []string
+	// 打印最终生效的 rest.Config (敏感信息会被隐藏)
+	Debug bool
+)
+
+// clientConfig 根据 flag 构建 kubeconfig 的加载规则
+// ExplicitPath 为空时, 默认规则会读取 KUBECONFIG 环境变量并合并其中的所有文件
+func clientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = Kubeconfig
+
+	overrides := &clientcmd.ConfigOverrides{
+		CurrentContext: Context,
+	}
+	overrides.Context.Namespace = Namespace
+
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+// RESTConfig 返回连接集群使用的 rest.Config, 并应用限流/超时/身份模拟等参数
+// 没有找到任何 kubeconfig 时, clientcmd 会自动回退到 inCluster 模式
+func RESTConfig() (*rest.Config, error) {
+	config, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	applyTuning(config)
+
+	if Debug {
+		logConfig(config)
+	}
+	return config, nil
+}
+
+func loadConfig() (*rest.Config, error) {
+	if InCluster {
+		config, err := rest.InClusterConfig()
+		if err != nil {
+			return nil, fmt.Errorf("load in-cluster config: %w", err)
+		}
+		return config, nil
+	}
+
+	config, err := clientConfig().ClientConfig()
+	if err != nil {
+		return nil, fmt.Errorf("load kubeconfig: %w", err)
+	}
+	return config, nil
+}
+
+// applyTuning 把命令行参数覆盖到 config 上, 未设置的参数保留 kubeconfig 或 client-go 的默认值
+func applyTuning(config *rest.Config) {
+	if QPS > 0 {
+		config.QPS = QPS
+	}
+	if Burst > 0 {
+		config.Burst = Burst
+	}
+	if Timeout > 0 {
+		config.Timeout = Timeout
+	}
+	if UserAgent != "" {
+		config.UserAgent = UserAgent
+	} else if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+	if Impersonate != "" {
+		config.Impersonate.UserName = Impersonate
+	}
+	if len(ImpersonateGroups) > 0 {
+		config.Impersonate.Groups = ImpersonateGroups
+	}
+}
+
+// logConfig 打印最终生效的配置, token/密码/证书等信息只打印是否存在
+func logConfig(config *rest.Config) {
+	log.Printf("[debug] host=%s apiPath=%s userAgent=%q\n", config.Host, config.APIPath, config.UserAgent)
+	log.Printf("[debug] qps=%v burst=%d timeout=%v\n", config.QPS, config.Burst, config.Timeout)
+	log.Printf("[debug] impersonate user=%q groups=%v\n", config.Impersonate.UserName, config.Impersonate.Groups)
+	log.Printf("[debug] auth: bearerToken=%s bearerTokenFile=%q username=%q password=%s execProvider=%t authProvider=%t\n",
+		redact(config.BearerToken), config.BearerTokenFile, config.Username, redact(config.Password),
+		config.ExecProvider != nil, config.AuthProvider != nil)
+	log.Printf("[debug] tls: insecure=%t serverName=%q caFile=%q certFile=%q keyFile=%q caData=%s certData=%s keyData=%s\n",
+		config.Insecure, config.ServerName, config.CAFile, config.CertFile, config.KeyFile,
+		redact(string(config.CAData)), redact(string(config.CertData)), redact(string(config.KeyData)))
+}
+
+func redact(secret string) string {
+	if secret == "" {
+		return "<empty>"
+	}
+	return "<redacted>"
+}
+
+// NewClientSet 使用 RESTConfig 创建 ClientSet 实例
+func NewClientSet() (*kubernetes.Clientset, error) {
+	config, err := RESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(config)
+}
+
+// NamespaceOr 返回 --namespace 指定的 namespace, 没有指定时返回 fallback
+func NamespaceOr(fallback string) string {
+	if Namespace != "" {
+		return Namespace
+	}
+	return fallback
+}
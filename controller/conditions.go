@@ -0,0 +1,114 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+// StatusClient 是 typed client 中通过 status 子资源更新对象的方法, 例如 clientset.AppsV1().Deployments(namespace)
+type StatusClient[T runtime.Object] interface {
+	UpdateStatus(ctx context.Context, obj T, opts metav1.UpdateOptions) (T, error)
+}
+
+// SetConditions 把 conditions 合并到 existing 中, 返回是否有变化
+// 同类型的 condition 只有 status 改变时才更新 lastTransitionTime, observedGeneration 为 0 时使用 generation
+func SetConditions(existing *[]metav1.Condition, generation int64, conditions ...metav1.Condition) bool {
+	changed := false
+	for _, condition := range conditions {
+		if condition.ObservedGeneration == 0 {
+			condition.ObservedGeneration = generation
+		}
+		if meta.SetStatusCondition(existing, condition) {
+			changed = true
+		}
+	}
+	return changed
+}
+
+// UpdateStatusIfChanged 在 updated 和 original 不同时通过 status 子资源写入 updated, 否则不发请求直接返回 original
+// updated 应该是 original 的深拷贝, 冲突等错误交给调用者返回, 由工作队列重试
+func UpdateStatusIfChanged[T runtime.Object](ctx context.Context, client StatusClient[T], original, updated T) (T, error) {
+	if equality.Semantic.DeepEqual(original, updated) {
+		return original, nil
+	}
+	result, err := client.UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return original, fmt.Errorf("update status: %w", err)
+	}
+	return result, nil
+}
+
+// UpdateConditions 把 conditions 合并到 obj 的 status 中并写入, conditionsOf 返回拷贝后对象的 conditions 字段
+// 没有变化时不会发请求, obj 本身不会被修改, 可以直接传入本地存储中的对象
+func UpdateConditions[T runtime.Object](ctx context.Context, client StatusClient[T], obj T, conditionsOf func(T) *[]metav1.Condition, conditions ...metav1.Condition) (T, error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return obj, err
+	}
+	updated, ok := obj.DeepCopyObject().(T)
+	if !ok {
+		return obj, fmt.Errorf("deep copy of %T has unexpected type", obj)
+	}
+	if !SetConditions(conditionsOf(updated), accessor.GetGeneration(), conditions...) {
+		return obj, nil
+	}
+	return UpdateStatusIfChanged(ctx, client, obj, updated)
+}
+
+// UpdateUnstructuredConditions 和 UpdateConditions 一样, 用于 dynamic client 获取的对象, conditions 位于 status.conditions
+func UpdateUnstructuredConditions(ctx context.Context, client dynamic.ResourceInterface, obj *unstructured.Unstructured, conditions ...metav1.Condition) (*unstructured.Unstructured, error) {
+	existing, err := UnstructuredConditions(obj)
+	if err != nil {
+		return obj, err
+	}
+	if !SetConditions(&existing, obj.GetGeneration(), conditions...) {
+		return obj, nil
+	}
+
+	items := make([]interface{}, 0, len(existing))
+	for i := range existing {
+		item, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&existing[i])
+		if err != nil {
+			return obj, err
+		}
+		items = append(items, item)
+	}
+	updated := obj.DeepCopy()
+	if err := unstructured.SetNestedSlice(updated.Object, items, "status", "conditions"); err != nil {
+		return obj, err
+	}
+
+	result, err := client.UpdateStatus(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return obj, fmt.Errorf("update status of %s/%s: %w", obj.GetNamespace(), obj.GetName(), err)
+	}
+	return result, nil
+}
+
+// UnstructuredConditions 读取 status.conditions, 字段不存在时返回空
+func UnstructuredConditions(obj *unstructured.Unstructured) ([]metav1.Condition, error) {
+	items, _, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+	conditions := make([]metav1.Condition, 0, len(items))
+	for _, item := range items {
+		fields, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("status.conditions of %s/%s contains %T, expected an object", obj.GetNamespace(), obj.GetName(), item)
+		}
+		var condition metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(fields, &condition); err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	policyv1 "k8s.io/api/policy/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var (
+	firstTransition  = metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	secondTransition = metav1.NewTime(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC))
+)
+
+func readyCondition(status metav1.ConditionStatus, reason string, transition metav1.Time) metav1.Condition {
+	return metav1.Condition{Type: "Ready", Status: status, Reason: reason, Message: reason, LastTransitionTime: transition}
+}
+
+// statusUpdates 返回通过 status 子资源写入的次数
+func statusUpdates(actions []k8stesting.Action) int {
+	n := 0
+	for _, action := range actions {
+		if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+			n++
+		}
+	}
+	return n
+}
+
+func pdbConditions(pdb *policyv1.PodDisruptionBudget) *[]metav1.Condition {
+	return &pdb.Status.Conditions
+}
+
+func TestUpdateConditions(t *testing.T) {
+	ctx := context.Background()
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 3}}
+	clientset := fake.NewSimpleClientset(pdb)
+	client := clientset.PolicyV1().PodDisruptionBudgets("default")
+
+	pdb, err := UpdateConditions(ctx, client, pdb, pdbConditions, readyCondition(metav1.ConditionFalse, "Pending", firstTransition))
+	if err != nil {
+		t.Fatalf("UpdateConditions() error = %v", err)
+	}
+	condition := meta.FindStatusCondition(pdb.Status.Conditions, "Ready")
+	if condition == nil || condition.ObservedGeneration != 3 || !condition.LastTransitionTime.Equal(&firstTransition) {
+		t.Fatalf("condition = %+v, want observedGeneration 3 and the first transition time", condition)
+	}
+
+	// status 不变时只更新 reason, 保留 lastTransitionTime
+	pdb, err = UpdateConditions(ctx, client, pdb, pdbConditions, readyCondition(metav1.ConditionFalse, "StillPending", secondTransition))
+	if err != nil {
+		t.Fatalf("UpdateConditions() error = %v", err)
+	}
+	condition = meta.FindStatusCondition(pdb.Status.Conditions, "Ready")
+	if condition.Reason != "StillPending" || !condition.LastTransitionTime.Equal(&firstTransition) {
+		t.Errorf("condition = %+v, want reason StillPending with the first transition time", condition)
+	}
+
+	// status 改变时更新 lastTransitionTime
+	pdb, err = UpdateConditions(ctx, client, pdb, pdbConditions, readyCondition(metav1.ConditionTrue, "Ready", secondTransition))
+	if err != nil {
+		t.Fatalf("UpdateConditions() error = %v", err)
+	}
+	condition = meta.FindStatusCondition(pdb.Status.Conditions, "Ready")
+	if condition.Status != metav1.ConditionTrue || !condition.LastTransitionTime.Equal(&secondTransition) {
+		t.Errorf("condition = %+v, want True with the second transition time", condition)
+	}
+	if n := statusUpdates(clientset.Actions()); n != 3 {
+		t.Errorf("status updates = %d, want 3", n)
+	}
+
+	stored, err := client.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get pdb: %v", err)
+	}
+	if !meta.IsStatusConditionTrue(stored.Status.Conditions, "Ready") {
+		t.Errorf("stored conditions = %+v, want Ready=True", stored.Status.Conditions)
+	}
+}
+
+func TestUpdateConditionsSkipsUnchanged(t *testing.T) {
+	ctx := context.Background()
+	pdb := &policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", Generation: 1}}
+	ready := readyCondition(metav1.ConditionTrue, "Ready", firstTransition)
+	ready.ObservedGeneration = 1
+	pdb.Status.Conditions = []metav1.Condition{ready}
+	clientset := fake.NewSimpleClientset(pdb)
+
+	// 本地存储中的对象不会被修改
+	ready.LastTransitionTime = secondTransition
+	got, err := UpdateConditions(ctx, clientset.PolicyV1().PodDisruptionBudgets("default"), pdb, pdbConditions, ready)
+	if err != nil {
+		t.Fatalf("UpdateConditions() error = %v", err)
+	}
+	if got != pdb {
+		t.Error("UpdateConditions() returned a new object for unchanged conditions")
+	}
+	if n := statusUpdates(clientset.Actions()); n != 0 {
+		t.Errorf("status updates = %d, want none", n)
+	}
+}
+
+var webAppGVR = schema.GroupVersionResource{Group: "demo.xlcbingo1999.io", Version: "v1alpha1", Resource: "webapps"}
+
+func newUnstructuredWebApp() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("demo.xlcbingo1999.io/v1alpha1")
+	obj.SetKind("WebApp")
+	obj.SetNamespace("default")
+	obj.SetName("web")
+	obj.SetGeneration(2)
+	return obj
+}
+
+func TestUpdateUnstructuredConditions(t *testing.T) {
+	ctx := context.Background()
+	obj := newUnstructuredWebApp()
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{webAppGVR: "WebAppList"}, obj)
+	client := dynamicClient.Resource(webAppGVR).Namespace("default")
+
+	obj, err := UpdateUnstructuredConditions(ctx, client, obj, readyCondition(metav1.ConditionFalse, "Pending", firstTransition))
+	if err != nil {
+		t.Fatalf("UpdateUnstructuredConditions() error = %v", err)
+	}
+	// 再次写入相同的 condition 不发请求
+	if _, err := UpdateUnstructuredConditions(ctx, client, obj, readyCondition(metav1.ConditionFalse, "Pending", secondTransition)); err != nil {
+		t.Fatalf("UpdateUnstructuredConditions() error = %v", err)
+	}
+	if n := statusUpdates(dynamicClient.Actions()); n != 1 {
+		t.Errorf("status updates = %d, want 1", n)
+	}
+
+	obj, err = UpdateUnstructuredConditions(ctx, client, obj, readyCondition(metav1.ConditionTrue, "Ready", secondTransition))
+	if err != nil {
+		t.Fatalf("UpdateUnstructuredConditions() error = %v", err)
+	}
+
+	stored, err := client.Get(ctx, "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get webapp: %v", err)
+	}
+	conditions, err := UnstructuredConditions(stored)
+	if err != nil {
+		t.Fatalf("UnstructuredConditions() error = %v", err)
+	}
+	condition := meta.FindStatusCondition(conditions, "Ready")
+	if condition == nil || condition.Status != metav1.ConditionTrue || condition.ObservedGeneration != 2 ||
+		!condition.LastTransitionTime.Equal(&secondTransition) {
+		t.Errorf("stored condition = %+v, want Ready=True at generation 2 with the second transition time", condition)
+	}
+}
+
+func TestUnstructuredConditionsRejectsMalformedStatus(t *testing.T) {
+	obj := newUnstructuredWebApp()
+	if conditions, err := UnstructuredConditions(obj); err != nil || len(conditions) != 0 {
+		t.Errorf("UnstructuredConditions() = %v, %v, want no conditions for a missing status", conditions, err)
+	}
+
+	obj.Object["status"] = map[string]interface{}{"conditions": []interface{}{"Ready"}}
+	if _, err := UnstructuredConditions(obj); err == nil {
+		t.Error("UnstructuredConditions() error = nil for a condition that is not an object")
+	}
+}
@@ -1,74 +1,332 @@
 package controller
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"path/filepath"
+	"net/http"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
-	"k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+	"github.com/xlcbingo1999/example-client-go/httpserver"
+	"github.com/xlcbingo1999/example-client-go/metrics"
+	"github.com/xlcbingo1999/example-client-go/probe"
 )
 
+// controller_demo 监听的范围, namespace 通过全局的 --namespace 指定
 var (
-	Kubeconfig string
-	config     *rest.Config
-	err        error
+	// 主资源, pods 或 deployments
+	Resource      string
+	AllNamespaces bool
+	LabelSelector string
+	// 例如 spec.nodeName=node1 只监听调度到某个节点上的 pod
+	FieldSelector string
+
+	// 收到退出信号后等待正在处理的 key 完成的最长时间
+	ShutdownTimeout time.Duration
+	// 提供 /metrics 的监听地址, 为空时不启动
+	MetricsAddr string
+	// 提供 /healthz, /readyz 和 /debug 的监听地址, 为空时不启动
+	ProbeAddr string
+
+	// 失败重试的策略
+	MaxRetries      int
+	RateLimiter     RateLimiterConfig
+	DeadLetterLimit int
+
+	// 是否在处理的 pod 上发送 Event, Component 是事件的来源
+	RecordEvents bool
+	Component    string
+	EventLimit   EventRateLimit
+
+	// 不为空时给处理的对象加上这个 finalizer, 对象删除前先执行清理, 为空时不加
+	FinalizerName string
 )
 
-type Controller struct {
-	indexer  cache.Indexer                   // 本地存储 负责存储完整资源信息的对象
-	queue    workqueue.RateLimitingInterface // 业务逻辑的工作队列
-	informer cache.Controller
+// Controller 监听 T 类型的资源, 把发生变化的对象的 key 放入工作队列, 再由 worker 交给 Reconciler 处理
+// T 必须是指针类型, 例如 *v1.Pod
+type Controller[T runtime.Object] struct {
+	name     string
+	store    Store[T]       // 本地存储 负责存储完整资源信息的对象
+	queue    *trackingQueue // 业务逻辑的工作队列
+	informer cache.SharedIndexInformer
+	// 通过 Watches 添加的次要资源的 informer
+	secondaries []cache.SharedIndexInformer
+	reconciler  Reconciler
+	recorder    record.EventRecorder
+
+	drainTimeout time.Duration
+	maxRetries   int
+	deadLetters  *deadLetters
+	// 收到退出信号后不再处理新的 key
+	stopping atomic.Bool
+	// 正在 Reconcile 的 key 的数量
+	inFlight atomic.Int32
+	// 启动的 worker 数量和仍在运行的 worker 数量, 用于 /healthz
+	workers      atomic.Int32
+	aliveWorkers atomic.Int32
+}
+
+type options struct {
+	resyncPeriod time.Duration
+	indexers     cache.Indexers
+	rateLimiter  workqueue.RateLimiter
+	drainTimeout time.Duration
+	maxRetries   int
+	// 最多保留的被丢弃的 key 的数量
+	deadLetterLimit int
+	recorder        record.EventRecorder
+}
+
+// Option 修改 Controller 的可选配置
+type Option func(*options)
+
+// WithResyncPeriod 设置 informer 周期性把本地存储中所有对象重新入队的间隔, 0 表示不 resync
+func WithResyncPeriod(period time.Duration) Option {
+	return func(o *options) { o.resyncPeriod = period }
+}
+
+// WithIndexers 为本地存储添加额外的索引
+func WithIndexers(indexers cache.Indexers) Option {
+	return func(o *options) { o.indexers = indexers }
+}
+
+// WithRateLimiter 设置工作队列的限速器
+func WithRateLimiter(rateLimiter workqueue.RateLimiter) Option {
+	return func(o *options) { o.rateLimiter = rateLimiter }
+}
+
+// WithDrainTimeout 设置退出时等待正在处理的 key 完成的最长时间
+func WithDrainTimeout(timeout time.Duration) Option {
+	return func(o *options) { o.drainTimeout = timeout }
+}
+
+// WithMaxRetries 设置处理失败的 key 最多重试的次数, 超过后放入 dead letter 记录
+func WithMaxRetries(maxRetries int) Option {
+	return func(o *options) { o.maxRetries = maxRetries }
+}
+
+// WithDeadLetterLimit 设置最多保留多少个被丢弃的 key, 0 表示不记录
+func WithDeadLetterLimit(limit int) Option {
+	return func(o *options) { o.deadLetterLimit = limit }
+}
+
+// WithRecorder 设置 Reconciler 发送 Event 使用的 EventRecorder, 测试时可以使用 record.FakeRecorder
+func WithRecorder(recorder record.EventRecorder) Option {
+	return func(o *options) { o.recorder = recorder }
+}
+
+// NewController 创建监听 lw 返回的资源的 Controller, 如果 reconciler 实现了 StoreInjector 会注入本地存储
+func NewController[T runtime.Object](name string, lw cache.ListerWatcher, reconciler Reconciler, opts ...Option) *Controller[T] {
+	o := newOptions(opts)
+
+	// informer 需要一个 T 类型的空对象来确定监听的资源类型
+	informer := cache.NewSharedIndexInformer(lw, newObject[T](), o.resyncPeriod, o.indexers)
+
+	c, err := NewControllerFromInformer[T](name, informer, reconciler, opts...)
+	// informer 还没有启动, 不会出错
+	utilruntime.Must(err)
+	return c
+}
+
+// NewControllerFromInformer 使用已有的 informer 创建 Controller, 例如从 SharedInformerFactory 中获取的 informer,
+// 这时 WithResyncPeriod 和 WithIndexers 不起作用, informer 已经停止时返回错误
+func NewControllerFromInformer[T runtime.Object](name string, informer cache.SharedIndexInformer, reconciler Reconciler, opts ...Option) (*Controller[T], error) {
+	o := newOptions(opts)
+
+	// 创建一个WorkerQueue, 这是一个限速队列
+	// 限速队列: 需要周期性遍历执行，执行完毕需要再次执行，执行失败需要延时再次执行
+	// 有名字的队列才会上报 workqueue 指标
+	queue := newTrackingQueue(o.rateLimiter, name)
+
+	c := &Controller[T]{
+		name:       name,
+		store:      NewStore[T](informer.GetIndexer()),
+		queue:      queue,
+		informer:   informer,
+		reconciler: reconciler,
+		recorder:   o.recorder,
+
+		drainTimeout: o.drainTimeout,
+		maxRetries:   o.maxRetries,
+		deadLetters:  newDeadLetters(o.deadLetterLimit),
+	}
+	if injector, ok := reconciler.(StoreInjector[T]); ok {
+		injector.InjectStore(c.store)
+	}
+	if injector, ok := reconciler.(RecorderInjector); ok {
+		injector.InjectRecorder(c.recorder)
+	}
+	metrics.RegisterInformer(name, c.hasSynced)
+
+	// 内部核心的业务逻辑是三个增删改函数, 都只是把 key 放入工作队列
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			c.enqueue(cache.MetaNamespaceKeyFunc, obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			c.enqueue(cache.MetaNamespaceKeyFunc, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			c.enqueue(cache.DeletionHandlingMetaNamespaceKeyFunc, obj)
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("add event handler to %s informer: %w", name, err)
+	}
+	return c, nil
 }
 
-func NewController(indexer cache.Indexer, queue workqueue.RateLimitingInterface, informer cache.Controller) *Controller {
-	return &Controller{
-		indexer:  indexer,
-		informer: informer,
-		queue:    queue,
+func newOptions(opts []Option) options {
+	o := options{
+		indexers:     cache.Indexers{},
+		rateLimiter:  workqueue.DefaultControllerRateLimiter(),
+		drainTimeout: 30 * time.Second,
+		maxRetries:   5,
+
+		deadLetterLimit: 100,
+		recorder:        discardRecorder{},
 	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newObject 通过反射创建 T 指向的类型的零值
+func newObject[T runtime.Object]() T {
+	var zero T
+	return reflect.New(reflect.TypeOf(zero).Elem()).Interface().(T)
+}
+
+func (c *Controller[T]) enqueue(keyFunc cache.KeyFunc, obj interface{}) {
+	key, err := keyFunc(obj)
+	if err != nil {
+		utilruntime.HandleError(err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+// Store 返回 informer 的本地存储
+func (c *Controller[T]) Store() Store[T] {
+	return c.store
+}
+
+// Recorder 返回 Controller 的 EventRecorder, 用于在处理的对象上发送 Normal/Warning 事件
+func (c *Controller[T]) Recorder() record.EventRecorder {
+	return c.recorder
+}
+
+// Informer 返回 Controller 使用的 informer, 可以用来添加额外的事件处理函数
+func (c *Controller[T]) Informer() cache.SharedIndexInformer {
+	return c.informer
 }
 
-func (c *Controller) runWorker() {
+// RegisterProbes 把 worker 存活检查、informer 就绪检查以及队列和本地存储的调试信息注册到 probe server
+func (c *Controller[T]) RegisterProbes(server *probe.Server) {
+	server.AddHealthz(c.name+"-workers", func() error {
+		// 还没有启动 worker(例如没有当选 leader)或者正在退出时不算失败
+		if c.stopping.Load() {
+			return nil
+		}
+		if workers, alive := c.workers.Load(), c.aliveWorkers.Load(); alive < workers {
+			return fmt.Errorf("%d of %d workers alive", alive, workers)
+		}
+		return nil
+	})
+	server.AddReadyz(c.name+"-informer", func() error {
+		if !c.hasSynced() {
+			return fmt.Errorf("informers have not synced")
+		}
+		return nil
+	})
+	server.AddDebug(c.name+"-queue", func() interface{} {
+		return c.queue.Snapshot()
+	})
+	server.AddDebug(c.name+"-cache", func() interface{} {
+		return probe.CacheKeyCounts(c.informer.GetStore().ListKeys())
+	})
+	server.AddHandler("/debug/"+c.name+"/dead-letters", http.HandlerFunc(c.serveDeadLetters))
+}
+
+func (c *Controller[T]) runWorker(ctx context.Context) {
 	// 死循环 一直执行逻辑
-	for c.processNextItem() {
+	for c.processNextItem(ctx) {
 
 	}
 }
 
-func (c *Controller) Run(workers int, stopCh chan struct{}) {
-	defer runtime.HandleCrash()
+// Run 启动 informer 和 workers, 阻塞直到 ctx 被取消
+// ctx 被取消后不再处理新的 key, 等待正在处理的 key 完成, 超过 drainTimeout 时返回 *DrainError
+// 因为失去 Lease 被取消时不等待, 直接取消正在执行的 Reconcile
+func (c *Controller[T]) Run(ctx context.Context, workers int) error {
+	defer utilruntime.HandleCrash()
 
-	defer c.queue.ShutDown() // 这个状态会被内部捕获到的
-	klog.Info("Starting Pod Controller")
+	klog.Infof("Starting %s controller", c.name)
 
-	go c.informer.Run(stopCh) // 开始接受从apiserver发出来的资源变更事件，并更新本地存储
+	// 开始接受从apiserver发出来的资源变更事件，并更新本地存储
+	// 来自 SharedInformerFactory 的 informer 如果已经被 factory 启动, 再次 Run 只会打印一条告警
+	for _, informer := range c.informers() {
+		go informer.Run(ctx.Done())
+	}
 	// 必须等到apiserver和本地存储实现同步才可以继续
-	if !cache.WaitForCacheSync(stopCh, c.informer.HasSynced) {
-		runtime.HandleError(fmt.Errorf("Time out waiting for cache to sync"))
-		return
+	if !cache.WaitForNamedCacheSync(c.name, ctx.Done(), c.hasSynced) {
+		c.queue.ShutDown()
+		// 同步完成之前就收到了退出信号, 没有需要等待的工作
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fmt.Errorf("timed out waiting for %s caches to sync", c.name)
 	}
 
+	// worker 使用独立的 ctx, 收到退出信号后正在执行的 Reconcile 不会马上被取消, 超过期限才取消
+	workerCtx, cancelWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWorkers()
+
 	// 并发启动worker, 从工作队列里面拿数据, 然后执行业务逻辑
+	var workersDone sync.WaitGroup
+	c.workers.Store(int32(workers))
 	for i := 0; i < workers; i++ {
-		go wait.Until(c.runWorker, time.Second, stopCh)
+		workersDone.Add(1)
+		c.aliveWorkers.Add(1)
+		go func() {
+			defer workersDone.Done()
+			defer c.aliveWorkers.Add(-1)
+			c.runWorker(workerCtx)
+		}()
 	}
 
-	<-stopCh
-	klog.Info("Stopping Pod Controller")
+	<-ctx.Done()
+	if errors.Is(context.Cause(ctx), errLeadershipLost) {
+		c.abort(cancelWorkers)
+		return nil
+	}
+	klog.Infof("Stopping %s controller, waiting up to %v for %d in-flight key(s)", c.name, c.drainTimeout, c.inFlight.Load())
+	return c.drain(&workersDone, cancelWorkers)
 }
 
-func (c *Controller) processNextItem() bool {
+func (c *Controller[T]) processNextItem(ctx context.Context) bool {
 	// 阻塞等待直到有数据可以从工作队列里面被取出
 	key, quit := c.queue.Get()
 
@@ -80,125 +338,178 @@ func (c *Controller) processNextItem() bool {
 	// 将key从工作队列里面删除
 	defer c.queue.Done(key)
 
+	// 正在退出时队列里剩下的 key 不再处理
+	if c.stopping.Load() {
+		return false
+	}
+
 	// 调用业务方法，实现具体的业务需求
-	err := c.syncToStdout(key.(string))
-	c.handleErr(err, key)
+	c.inFlight.Add(1)
+	defer c.inFlight.Add(-1)
+	start := time.Now()
+	result, err := c.reconciler.Reconcile(ctx, key.(string))
+	metrics.ObserveReconcile(c.name, resultLabel(result, err), time.Since(start))
+	c.handleResult(key, result, err)
 	return true
 }
 
-func (c *Controller) syncToStdout(key string) error {
-	// 根据key从本地存储中获取pod信息, 因为有长连接和apiserver保持同步, 因此本地的pod信息是和集群一致的
-	obj, exists, err := c.indexer.GetByKey(key)
-	if err != nil {
-		klog.Errorf("Fetching object with key %s from store failed with %v", key, err)
-		return err
+func resultLabel(result Result, err error) string {
+	switch {
+	case err != nil:
+		return metrics.ResultError
+	case result.RequeueAfter > 0:
+		return metrics.ResultRequeueAfter
+	case result.Requeue:
+		return metrics.ResultRequeue
 	}
-
-	if !exists {
-		log.Printf("Pod %s does not exist anymore\n", key)
-	} else {
-		// 这里就是真正的业务逻辑代码了，一般会比较spce和status的差异，然后做出处理使得status与spce保持一致，
-		// 此处为了代码简单仅仅打印一行日志
-		log.Printf("Sync/Add/Update for Pod %s\n", obj.(*v1.Pod).GetName())
-	}
-	return nil
+	return metrics.ResultSuccess
 }
 
-func (c *Controller) handleErr(err error, key interface{}) {
+func (c *Controller[T]) handleResult(key interface{}, result Result, err error) {
 	// 没有错误时的处理逻辑
 	if err == nil {
 		// 确认这个key已经被成功处理，在队列中彻底清理掉
 		// 假设之前在处理该key的时候曾报错导致重新进入队列等待重试，那么也会因为这个Forget方法而不再被重试
 		c.queue.Forget(key)
+		// 之前被丢弃的 key 因为对象变化重新入队并处理成功了
+		c.deadLetters.remove(key.(string))
+		switch {
+		case result.RequeueAfter > 0:
+			c.queue.AddAfter(key, result.RequeueAfter)
+		case result.Requeue:
+			c.queue.AddRateLimited(key)
+		}
 		return
 	}
 
 	// 代码走到这里表示前面执行业务逻辑的时候发生了错误，
-	// 检查已经重试的次数，如果不操作5次就继续重试，这里可以根据实际需求定制
-	if c.queue.NumRequeues(key) < 5 {
-		klog.Infof("Error syncing pod %v: %v", key, err)
+	// 检查已经重试的次数，如果不超过maxRetries次就继续重试
+	attempts := c.queue.NumRequeues(key) + 1
+	if attempts <= c.maxRetries {
+		klog.Infof("Error syncing %s %v (attempt %d): %v", c.name, key, attempts, err)
 		c.queue.AddRateLimited(key)
 		return
 	}
 
-	// 如果重试超过了5次就彻底放弃了，也像执行成功那样调用Forget做彻底清理（否则就没完没了了）
+	// 如果重试超过了maxRetries次就彻底放弃了，也像执行成功那样调用Forget做彻底清理（否则就没完没了了）
 	c.queue.Forget(key)
+	// 记录下被丢弃的 key, 之后可以通过 /debug/<name>/dead-letters 查看和重新入队
+	c.deadLetters.add(DeadLetter{
+		Key:       key.(string),
+		LastError: err.Error(),
+		Attempts:  attempts,
+		DroppedAt: time.Now(),
+	})
 	// 向外部报告错误，走通用的错误处理流程
-	runtime.HandleError(err)
-	klog.Infof("Dropping pod %q out of the queue: %v", key, err)
+	utilruntime.HandleError(err)
+	klog.Infof("Dropping %s %q out of the queue after %d attempts: %v", c.name, key, attempts, err)
 }
 
-func RunController() {
-	// home是家目录，如果能取得家目录的值，就可以用来做默认值
-	if home := homedir.HomeDir(); home != "" {
-		// 如果输入了kubeconfig参数，该参数的值就是kubeconfig文件的绝对路径，
-		// 如果没有输入kubeconfig参数，就用默认路径~/.kube/config
-		Kubeconfig = filepath.Join(home, ".kube", "config")
+func RunController() error {
+	// 创建 Clientset 对象
+	clientset, err := connection.NewClientSet()
+	if err != nil {
+		return err
+	}
+
+	rateLimiter, err := NewRateLimiter(RateLimiter)
+	if err != nil {
+		return err
 	}
 
-	if config, err = rest.InClusterConfig(); err != nil {
-		if config, err = clientcmd.BuildConfigFromFlags("", Kubeconfig); err != nil {
-			panic(err.Error())
-		}
+	opts := []Option{
+		WithDrainTimeout(ShutdownTimeout),
+		WithRateLimiter(rateLimiter),
+		WithMaxRetries(MaxRetries),
+		WithDeadLetterLimit(DeadLetterLimit),
+	}
+	if RecordEvents {
+		recorder, stopRecording := NewEventRecorder(clientset, Component, EventLimit)
+		defer stopRecording()
+		opts = append(opts, WithRecorder(recorder))
 	}
 
-	// 创建 Clientset 对象
-	clientset, err := kubernetes.NewForConfig(config)
+	controller, err := newResourceController(clientset, opts...)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
-	// 创建一个ListWatch对象, 指定监控的资源的pod和namespace为default
-	podListWatcher := cache.NewListWatchFromClient(
-		clientset.CoreV1().RESTClient(),
-		"pods",
-		v1.NamespaceDefault,
-		fields.Everything(), // 表示啥都要监控
-	)
+	// 一直运行到收到 SIGINT/SIGTERM, 开启选主时只有 leader 会启动 worker
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// 创建一个WorkerQueue, 这是一个限速队列
-	// 限速队列: 需要周期性遍历执行，执行完毕需要再次执行，执行失败需要延时再次执行
-	queue := workqueue.NewRateLimitingQueue(
-		workqueue.DefaultControllerRateLimiter(),
-	)
-
-	// 这里是创建一个Informer, 内部核心的业务逻辑是三个增删改函数
-	indexer, informer := cache.NewIndexerInformer(
-		podListWatcher,
-		&v1.Pod{},
-		0,
-		cache.ResourceEventHandlerFuncs{
-			AddFunc: func(obj interface{}) {
-				key, err := cache.MetaNamespaceKeyFunc(obj)
-				if err == nil {
-					queue.Add(key)
-				}
-			},
-			UpdateFunc: func(oldObj, newObj interface{}) {
-				key, err := cache.MetaNamespaceKeyFunc(newObj)
-				if err == nil {
-					queue.Add(key)
-				}
-			},
-			DeleteFunc: func(obj interface{}) {
-				key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
-				if err == nil {
-					queue.Add(key)
-				}
-			},
-		},
-		cache.Indexers{},
-	)
+	if err := httpserver.StartServers(ctx, MetricsAddr, ProbeAddr, controller.RegisterProbes); err != nil {
+		return err
+	}
+	if !LeaderElect {
+		return controller.Run(ctx, 1)
+	}
+	return runWithLeaderElection(ctx, clientset, func(ctx context.Context) error {
+		return controller.Run(ctx, 1)
+	})
+}
 
-	// 创建Controller对象，将所需的三个变量对象传入
-	controller := NewController(indexer, queue, informer)
+// runnable 是不同主资源的 Controller 共同的方法
+type runnable interface {
+	Run(ctx context.Context, workers int) error
+	RegisterProbes(server *probe.Server)
+}
 
-	// Now let's start the controller
-	stop := make(chan struct{})
-	defer close(stop)
-	// 在协程中启动controller
-	go controller.Run(1, stop)
+// newResourceController 根据 Resource 创建 Controller
+func newResourceController(clientset kubernetes.Interface, opts ...Option) (runnable, error) {
+	switch Resource {
+	case ResourcePods:
+		podListWatcher, err := podListWatch(clientset)
+		if err != nil {
+			return nil, err
+		}
+		// 创建Controller对象, 业务逻辑是把变化的pod打印出来
+		reconciler := NewStdoutReconciler[*v1.Pod]("Pod")
+		if FinalizerName != "" {
+			reconciler.Finalizer = NewFinalizer(FinalizerName, func(namespace string) ObjectClient[*v1.Pod] {
+				return clientset.CoreV1().Pods(namespace)
+			}, logCleanup[*v1.Pod])
+		}
+		return NewController[*v1.Pod]("pod", podListWatcher, reconciler, opts...), nil
+	case ResourceDeployments:
+		return newDeploymentController(clientset, opts...)
+	}
+	return nil, &exitcode.UsageError{Err: fmt.Errorf("unsupported resource %q, must be %s or %s", Resource, ResourcePods, ResourceDeployments)}
+}
 
-	// Wait forever
-	select {}
+// logCleanup 是演示用的清理函数, 只打印一行日志
+func logCleanup[T runtime.Object](ctx context.Context, obj T) error {
+	if accessor, err := meta.Accessor(obj); err == nil {
+		klog.Infof("Cleaning up %s/%s before it is deleted", accessor.GetNamespace(), accessor.GetName())
+	}
+	return nil
+}
+
+// podListWatch 创建只返回监听范围内的 pod 的 ListWatch, 过滤由 apiserver 完成
+func podListWatch(clientset kubernetes.Interface) (cache.ListerWatcher, error) {
+	labelSelector, err := labels.Parse(LabelSelector)
+	if err != nil {
+		return nil, &exitcode.UsageError{Err: fmt.Errorf("invalid label selector: %w", err)}
+	}
+	fieldSelector, err := fields.ParseSelector(FieldSelector)
+	if err != nil {
+		return nil, &exitcode.UsageError{Err: fmt.Errorf("invalid field selector: %w", err)}
+	}
+
+	// namespace默认为default, 空字符串表示所有 namespace
+	namespace := connection.NamespaceOr(v1.NamespaceDefault)
+	if AllNamespaces {
+		namespace = v1.NamespaceAll
+	}
+	klog.Infof("Watching pods in namespace %q with label selector %q and field selector %q", namespace, labelSelector, fieldSelector)
+
+	return cache.NewFilteredListWatchFromClient(
+		clientset.CoreV1().RESTClient(),
+		"pods",
+		namespace,
+		func(options *metav1.ListOptions) {
+			options.LabelSelector = labelSelector.String()
+			options.FieldSelector = fieldSelector.String()
+		},
+	), nil
 }
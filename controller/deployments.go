@@ -0,0 +1,206 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// controller_demo 处理的主资源
+const (
+	ResourcePods        = "pods"
+	ResourceDeployments = "deployments"
+)
+
+var (
+	deploymentGK = schema.GroupKind{Group: appsv1.GroupName, Kind: "Deployment"}
+	replicaSetGK = schema.GroupKind{Group: appsv1.GroupName, Kind: "ReplicaSet"}
+)
+
+// newDeploymentController 创建以 Deployment 为主资源的 Controller,
+// 它拥有的 ReplicaSet、Pod 以及选中它的 Pod 的 Service 变化时也会重新处理这个 Deployment
+func newDeploymentController(clientset kubernetes.Interface, opts ...Option) (*Controller[*appsv1.Deployment], error) {
+	// 这几种资源无法共用 pod 的过滤条件
+	if LabelSelector != "" || FieldSelector != "" {
+		return nil, &exitcode.UsageError{Err: fmt.Errorf("--selector and --field-selector are only supported with --resource=%s", ResourcePods)}
+	}
+
+	namespace := connection.NamespaceOr(v1.NamespaceDefault)
+	if AllNamespaces {
+		namespace = v1.NamespaceAll
+	}
+	klog.Infof("Watching deployments and their replicasets, pods and services in namespace %q", namespace)
+
+	// 所有资源的 informer 都来自同一个 factory, Controller.Run 会启动并等待它们同步
+	factory := informers.NewSharedInformerFactoryWithOptions(clientset, 0, informers.WithNamespace(namespace))
+	deployments := factory.Apps().V1().Deployments()
+	replicaSets := factory.Apps().V1().ReplicaSets()
+	pods := factory.Core().V1().Pods()
+	services := factory.Core().V1().Services()
+
+	reconciler := &deploymentReconciler{
+		replicaSets: replicaSets.Lister(),
+		pods:        pods.Lister(),
+		services:    services.Lister(),
+	}
+	if FinalizerName != "" {
+		reconciler.finalizer = NewFinalizer(FinalizerName, func(namespace string) ObjectClient[*appsv1.Deployment] {
+			return clientset.AppsV1().Deployments(namespace)
+		}, logCleanup[*appsv1.Deployment])
+	}
+	c, err := NewControllerFromInformer[*appsv1.Deployment]("deployment", deployments.Informer(), reconciler, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pod 的 owner 是 ReplicaSet, 需要通过 ReplicaSet 的本地存储找到 Deployment
+	intermediates := map[schema.GroupKind]cache.Store{replicaSetGK: replicaSets.Informer().GetStore()}
+	watches := []struct {
+		informer cache.SharedIndexInformer
+		mapFunc  MapFunc
+	}{
+		{replicaSets.Informer(), EnqueueOwner(deploymentGK, nil)},
+		{pods.Informer(), EnqueueOwner(deploymentGK, intermediates)},
+		{services.Informer(), deploymentsSelectedBy(deployments.Lister())},
+	}
+	for _, w := range watches {
+		if err := c.Watches(w.informer, w.mapFunc); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// deploymentsSelectedBy 返回 Service 的 selector 能选中其 Pod 模板的 Deployment, Service 没有 ownerReference 指向 Deployment
+func deploymentsSelectedBy(lister appslisters.DeploymentLister) MapFunc {
+	return func(obj interface{}) []string {
+		service, ok := obj.(*v1.Service)
+		if !ok || len(service.Spec.Selector) == 0 {
+			return nil
+		}
+		deployments, err := lister.Deployments(service.Namespace).List(labels.Everything())
+		if err != nil {
+			return nil
+		}
+
+		selector := labels.SelectorFromSet(service.Spec.Selector)
+		var keys []string
+		for _, deployment := range deployments {
+			if selector.Matches(labels.Set(deployment.Spec.Template.Labels)) {
+				keys = append(keys, deployment.Namespace+"/"+deployment.Name)
+			}
+		}
+		return keys
+	}
+}
+
+// deploymentReconciler 打印 Deployment 及其 ReplicaSet、Pod 和 Service 的汇总信息
+type deploymentReconciler struct {
+	store       Store[*appsv1.Deployment]
+	replicaSets appslisters.ReplicaSetLister
+	pods        corelisters.PodLister
+	services    corelisters.ServiceLister
+	recorder    record.EventRecorder
+	finalizer   *Finalizer[*appsv1.Deployment]
+}
+
+func (r *deploymentReconciler) InjectStore(store Store[*appsv1.Deployment]) {
+	r.store = store
+}
+
+func (r *deploymentReconciler) InjectRecorder(recorder record.EventRecorder) {
+	r.recorder = recorder
+}
+
+func (r *deploymentReconciler) Reconcile(ctx context.Context, key string) (Result, error) {
+	deployment, exists, err := r.store.Get(key)
+	if err != nil {
+		return Result{}, fmt.Errorf("fetching deployment %s from store failed: %w", key, err)
+	}
+	if !exists {
+		log.Printf("Deployment %s does not exist anymore\n", key)
+		return Result{}, nil
+	}
+	if r.finalizer != nil {
+		deleting, err := r.finalizer.Handle(ctx, deployment)
+		if err != nil || deleting {
+			return Result{}, err
+		}
+	}
+
+	replicaSets, err := r.replicaSets.ReplicaSets(deployment.Namespace).List(labels.Everything())
+	if err != nil {
+		return Result{}, err
+	}
+	owned := map[types.UID]bool{}
+	for _, rs := range replicaSets {
+		if isControlledBy(rs, deployment.UID) {
+			owned[rs.UID] = true
+		}
+	}
+
+	pods, err := r.pods.Pods(deployment.Namespace).List(labels.Everything())
+	if err != nil {
+		return Result{}, err
+	}
+	total, ready := 0, 0
+	for _, pod := range pods {
+		ref := metav1.GetControllerOfNoCopy(pod)
+		if ref == nil || !owned[ref.UID] {
+			continue
+		}
+		total++
+		if podReady(pod) {
+			ready++
+		}
+	}
+
+	services, err := r.services.Services(deployment.Namespace).List(labels.Everything())
+	if err != nil {
+		return Result{}, err
+	}
+	var serviceNames []string
+	for _, service := range services {
+		if len(service.Spec.Selector) > 0 &&
+			labels.SelectorFromSet(service.Spec.Selector).Matches(labels.Set(deployment.Spec.Template.Labels)) {
+			serviceNames = append(serviceNames, service.Name)
+		}
+	}
+
+	log.Printf("Sync Deployment %s: %d replicasets, %d/%d pods ready, services %v\n", key, len(owned), ready, total, serviceNames)
+	if r.recorder != nil {
+		r.recorder.Eventf(deployment, v1.EventTypeNormal, "Synced", "%d/%d pods ready, %d services", ready, total, len(serviceNames))
+	}
+	return Result{}, nil
+}
+
+func isControlledBy(obj metav1.Object, uid types.UID) bool {
+	ref := metav1.GetControllerOfNoCopy(obj)
+	return ref != nil && ref.UID == uid
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package controller
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+)
+
+// RecorderInjector 由需要发送 Event 的 Reconciler 实现, Controller 创建时会注入它的 EventRecorder
+type RecorderInjector interface {
+	InjectRecorder(recorder record.EventRecorder)
+}
+
+// discardRecorder 丢弃所有事件, 没有通过 WithRecorder 设置 EventRecorder 时使用
+type discardRecorder struct{}
+
+func (discardRecorder) Event(object runtime.Object, eventtype, reason, message string) {}
+
+func (discardRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+}
+
+func (discardRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+}
+
+// EventRateLimit 限制每个对象发送 Event 的速率, 为 0 的字段使用 client-go 的默认值
+type EventRateLimit struct {
+	// 每个对象每秒可以发送的事件数, client-go 默认是每 5 分钟 1 个
+	QPS float32
+	// 每个对象在限速之前最多可以连续发送的事件数, client-go 默认是 25
+	Burst int
+}
+
+// NewEventRecorder 创建把 Event 写入 apiserver 的 EventRecorder, 事件的 source.component 是 component
+// 相同的事件会由 broadcaster 合并计数, 超过速率限制的事件直接丢弃, 调用返回的 stop 停止发送
+func NewEventRecorder(clientset kubernetes.Interface, component string, limit EventRateLimit) (record.EventRecorder, func()) {
+	broadcaster := record.NewBroadcaster(record.WithCorrelatorOptions(record.CorrelatorOptions{
+		QPS:       limit.QPS,
+		BurstSize: limit.Burst,
+	}))
+	broadcaster.StartLogging(klog.Infof)
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component})
+	return recorder, broadcaster.Shutdown
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestStdoutReconcilerRecordsSyncedEvent(t *testing.T) {
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}}
+	recorder := record.NewFakeRecorder(10)
+	c := NewController[*v1.Pod]("events-test", podListWatchFor(fake.NewSimpleClientset()), NewStdoutReconciler[*v1.Pod]("Pod"),
+		WithRecorder(recorder))
+	if err := c.Informer().GetIndexer().Add(pod); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+
+	if _, err := c.reconciler.Reconcile(context.Background(), "default/web"); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.HasPrefix(event, v1.EventTypeNormal+" Synced ") {
+			t.Errorf("event = %q, want Normal Synced ...", event)
+		}
+	default:
+		t.Fatal("no event was recorded")
+	}
+
+	// 已经删除的对象不发送事件
+	if _, err := c.reconciler.Reconcile(context.Background(), "default/gone"); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	select {
+	case event := <-recorder.Events:
+		t.Errorf("unexpected event for a missing object: %q", event)
+	default:
+	}
+}
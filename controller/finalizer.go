@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+)
+
+// ObjectClient 是 typed client 中读取和 patch 单个对象的方法, 例如 clientset.CoreV1().Pods(namespace)
+type ObjectClient[T runtime.Object] interface {
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (T, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (T, error)
+}
+
+// CleanupFunc 在对象被删除之前清理它关联的外部资源, 返回错误时 finalizer 保留, 之后重试
+type CleanupFunc[T runtime.Object] func(ctx context.Context, obj T) error
+
+// Finalizer 让 Reconciler 在对象真正被删除之前执行清理
+// 对象第一次被处理时加上 finalizer, 带有 deletionTimestamp 时执行 Cleanup 然后移除 finalizer
+type Finalizer[T runtime.Object] struct {
+	// finalizer 的名字, 例如 example.com/cleanup
+	Name string
+	// 返回对象所在 namespace 的 client
+	Client  func(namespace string) ObjectClient[T]
+	Cleanup CleanupFunc[T]
+}
+
+func NewFinalizer[T runtime.Object](name string, client func(namespace string) ObjectClient[T], cleanup CleanupFunc[T]) *Finalizer[T] {
+	return &Finalizer[T]{Name: name, Client: client, Cleanup: cleanup}
+}
+
+// Handle 在对象没有被删除时确保它带有 finalizer, 对象正在被删除时执行 Cleanup 并移除 finalizer
+// deleting 为 true 表示对象正在被删除, Reconciler 不应该再继续处理
+func (f *Finalizer[T]) Handle(ctx context.Context, obj T) (deleting bool, err error) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false, err
+	}
+	has := slices.Contains(accessor.GetFinalizers(), f.Name)
+
+	if accessor.GetDeletionTimestamp() == nil {
+		if has {
+			return false, nil
+		}
+		return false, f.patch(ctx, obj, func(finalizers []string) []string {
+			if slices.Contains(finalizers, f.Name) {
+				return finalizers
+			}
+			return append(slices.Clone(finalizers), f.Name)
+		})
+	}
+
+	// 别的 finalizer 还在, 或者已经清理过了
+	if !has {
+		return true, nil
+	}
+	if f.Cleanup != nil {
+		if err := f.Cleanup(ctx, obj); err != nil {
+			return true, fmt.Errorf("cleanup %s/%s before removing finalizer %s: %w",
+				accessor.GetNamespace(), accessor.GetName(), f.Name, err)
+		}
+	}
+	return true, f.patch(ctx, obj, func(finalizers []string) []string {
+		return slices.DeleteFunc(slices.Clone(finalizers), func(s string) bool { return s == f.Name })
+	})
+}
+
+// patch 用 merge patch 修改 metadata.finalizers, 带上 resourceVersion 避免覆盖别人同时做的修改
+// 发生冲突时重新获取对象再试, 对象已经不存在时直接返回
+func (f *Finalizer[T]) patch(ctx context.Context, obj T, mutate func(finalizers []string) []string) error {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	namespace, name := accessor.GetNamespace(), accessor.GetName()
+	client := f.Client(namespace)
+
+	// 第一次使用本地存储中的对象, 冲突之后才从 apiserver 获取
+	current := obj
+	first := true
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if !first {
+			latest, err := client.Get(ctx, name, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			current = latest
+		}
+		first = false
+
+		accessor, err := meta.Accessor(current)
+		if err != nil {
+			return err
+		}
+		finalizers := mutate(accessor.GetFinalizers())
+		if slices.Equal(finalizers, accessor.GetFinalizers()) {
+			return nil
+		}
+
+		data, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"finalizers":      finalizers,
+				"resourceVersion": accessor.GetResourceVersion(),
+			},
+		})
+		if err != nil {
+			return err
+		}
+		klog.V(2).Infof("Patching finalizers of %s/%s to %v", namespace, name, finalizers)
+		_, err = client.Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+		return err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("patch finalizers of %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testFinalizer = "example.com/cleanup"
+
+func newTestPod(finalizers ...string) *v1.Pod {
+	return &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            "web",
+		Namespace:       "default",
+		ResourceVersion: "1",
+		Finalizers:      finalizers,
+	}}
+}
+
+// newTestFinalizer 返回的 cleaned 记录 Cleanup 被调用的次数
+func newTestFinalizer(clientset *fake.Clientset) (*Finalizer[*v1.Pod], *int) {
+	cleaned := 0
+	f := NewFinalizer(testFinalizer, func(namespace string) ObjectClient[*v1.Pod] {
+		return clientset.CoreV1().Pods(namespace)
+	}, func(ctx context.Context, pod *v1.Pod) error {
+		cleaned++
+		return nil
+	})
+	return f, &cleaned
+}
+
+func getFinalizers(t *testing.T, clientset *fake.Clientset) []string {
+	t.Helper()
+	pod, err := clientset.CoreV1().Pods("default").Get(context.Background(), "web", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get pod: %v", err)
+	}
+	return pod.Finalizers
+}
+
+// conflictOnce 让第一次 patch 返回 409, 返回值记录 patch 的次数
+func conflictOnce(clientset *fake.Clientset) *int {
+	patches := 0
+	clientset.PrependReactor("patch", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patches++
+		if patches == 1 {
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "pods"}, "web", nil)
+		}
+		return false, nil, nil
+	})
+	return &patches
+}
+
+func TestFinalizerAddedOnFirstHandle(t *testing.T) {
+	pod := newTestPod("other")
+	clientset := fake.NewSimpleClientset(pod)
+	f, cleaned := newTestFinalizer(clientset)
+
+	deleting, err := f.Handle(context.Background(), pod)
+	if err != nil || deleting {
+		t.Fatalf("Handle() = %v, %v, want false, nil", deleting, err)
+	}
+	if got, want := getFinalizers(t, clientset), []string{"other", testFinalizer}; !slices.Equal(got, want) {
+		t.Errorf("finalizers = %v, want %v", got, want)
+	}
+	if *cleaned != 0 {
+		t.Errorf("cleanup called %d times, want 0", *cleaned)
+	}
+	// 本地存储中的对象不能被修改
+	if !slices.Equal(pod.Finalizers, []string{"other"}) {
+		t.Errorf("input object was modified: %v", pod.Finalizers)
+	}
+}
+
+func TestFinalizerRemovedAfterCleanup(t *testing.T) {
+	pod := newTestPod("other", testFinalizer)
+	now := metav1.Now()
+	pod.DeletionTimestamp = &now
+	clientset := fake.NewSimpleClientset(pod)
+	f, cleaned := newTestFinalizer(clientset)
+
+	deleting, err := f.Handle(context.Background(), pod)
+	if err != nil || !deleting {
+		t.Fatalf("Handle() = %v, %v, want true, nil", deleting, err)
+	}
+	if *cleaned != 1 {
+		t.Errorf("cleanup called %d times, want 1", *cleaned)
+	}
+	if got, want := getFinalizers(t, clientset), []string{"other"}; !slices.Equal(got, want) {
+		t.Errorf("finalizers = %v, want %v", got, want)
+	}
+}
+
+func TestFinalizerRetriesOnConflict(t *testing.T) {
+	pod := newTestPod()
+	clientset := fake.NewSimpleClientset(pod)
+	patches := conflictOnce(clientset)
+	f, _ := newTestFinalizer(clientset)
+
+	if _, err := f.Handle(context.Background(), pod); err != nil {
+		t.Fatalf("Handle() error = %v", err)
+	}
+	if *patches != 2 {
+		t.Errorf("patched %d times, want 2", *patches)
+	}
+	// 冲突之后需要重新获取对象
+	gets := 0
+	for _, action := range clientset.Actions() {
+		if action.GetVerb() == "get" {
+			gets++
+		}
+	}
+	if gets != 1 {
+		t.Errorf("got the pod %d times after the conflict, want 1", gets)
+	}
+	if got, want := getFinalizers(t, clientset), []string{testFinalizer}; !slices.Equal(got, want) {
+		t.Errorf("finalizers = %v, want %v", got, want)
+	}
+}
+
+func TestFinalizerToleratesNotFound(t *testing.T) {
+	// 对象已经从 apiserver 中删除, 只剩本地存储中的旧对象
+	pod := newTestPod()
+	clientset := fake.NewSimpleClientset()
+	patches := conflictOnce(clientset)
+	f, _ := newTestFinalizer(clientset)
+
+	deleting, err := f.Handle(context.Background(), pod)
+	if err != nil || deleting {
+		t.Fatalf("Handle() = %v, %v, want false, nil", deleting, err)
+	}
+	if *patches != 1 {
+		t.Errorf("patched %d times, want 1", *patches)
+	}
+}
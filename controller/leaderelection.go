@@ -0,0 +1,154 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/klog"
+)
+
+// 选主使用的参数, 多个 controller_demo 副本之间只有持有 Lease 的副本会启动 worker
+var (
+	LeaderElect    bool
+	LeaseName      string
+	LeaseNamespace string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+	// 为空时使用 hostname 加随机后缀
+	LeaderIdentity string
+)
+
+// errLeadershipLost 是失去 Lease 时取消 run 的 ctx 的原因, Controller.Run 看到它时不再等待 drain
+var errLeadershipLost = errors.New("lost leadership")
+
+// runWithLeaderElection 竞选成功后才调用 run, ctx 被取消时 run 先完成 drain, 之后才释放 Lease,
+// 这样其它副本接手时这个副本一定已经停止了所有 worker
+// 续约失败丢失 Lease 时其它副本可能马上接手, 这时以 errLeadershipLost 取消 run 的 ctx, 不等待 drain
+func runWithLeaderElection(ctx context.Context, clientset kubernetes.Interface, run func(ctx context.Context) error) error {
+	identity, err := leaderIdentity()
+	if err != nil {
+		return err
+	}
+
+	// elector 使用独立的 ctx, ReleaseOnCancel 会在它被取消时释放 Lease, 所以只能在 run 返回之后取消
+	electionCtx, stopElection := context.WithCancel(context.WithoutCancel(ctx))
+	defer stopElection()
+	runCtx, stopRun := context.WithCancelCause(ctx)
+	defer stopRun(nil)
+
+	var (
+		mu      sync.Mutex
+		started bool
+		// run 正在执行, 只有这时失去 Lease 才需要取消 run
+		running bool
+		runErr  error
+		done    = make(chan struct{})
+	)
+
+	// 当选之前 ctx 被取消时直接结束选主, 当选之后由 run 返回时结束
+	go func() {
+		select {
+		case <-ctx.Done():
+			mu.Lock()
+			if !started {
+				stopElection()
+			}
+			mu.Unlock()
+		case <-electionCtx.Done():
+		}
+	}()
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta:  metav1.ObjectMeta{Name: LeaseName, Namespace: LeaseNamespace},
+			Client:     clientset.CoordinationV1(),
+			LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+		},
+		ReleaseOnCancel: true,
+		LeaseDuration:   LeaseDuration,
+		RenewDeadline:   RenewDeadline,
+		RetryPeriod:     RetryPeriod,
+		Name:            LeaseName,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(leaderCtx context.Context) {
+				// OnStartedLeading 在单独的协程中执行, 选主已经结束或者 ctx 已经被取消时不再启动 run
+				mu.Lock()
+				if leaderCtx.Err() != nil || ctx.Err() != nil {
+					mu.Unlock()
+					return
+				}
+				started = true
+				running = true
+				mu.Unlock()
+				defer close(done)
+
+				klog.Infof("%s became the leader of lease %s/%s", identity, LeaseNamespace, LeaseName)
+				err := run(runCtx)
+				mu.Lock()
+				running = false
+				runErr = err
+				mu.Unlock()
+				// run 完成 drain 之后才结束选主, 释放 Lease
+				stopElection()
+			},
+			OnStoppedLeading: func() {
+				klog.Infof("%s stopped leading", identity)
+				// run 返回之后释放 Lease 也会调用到这里, 这时不是失去 Lease
+				mu.Lock()
+				if running {
+					stopRun(errLeadershipLost)
+				}
+				mu.Unlock()
+			},
+			OnNewLeader: func(current string) {
+				if current != identity {
+					klog.Infof("Current leader is %s", current)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("leader election: %w", err)
+	}
+
+	elector.Run(electionCtx)
+
+	// 当选之前 ctx 被取消时 run 不会被调用, 否则等待 run 退出
+	mu.Lock()
+	leading := started
+	mu.Unlock()
+	if !leading {
+		return nil
+	}
+	<-done
+
+	if runErr != nil {
+		return runErr
+	}
+	if errors.Is(context.Cause(runCtx), errLeadershipLost) {
+		return fmt.Errorf("lost leadership of lease %s/%s", LeaseNamespace, LeaseName)
+	}
+	return runErr
+}
+
+func leaderIdentity() (string, error) {
+	if LeaderIdentity != "" {
+		return LeaderIdentity, nil
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("get hostname for leader identity: %w", err)
+	}
+	// 同一台机器上运行多个副本时也要区分开
+	return hostname + "_" + string(uuid.NewUUID()), nil
+}
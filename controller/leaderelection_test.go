@@ -0,0 +1,180 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+const testIdentity = "replica-a"
+
+// useTestLease 把选主参数改成很短的时间, 测试结束后恢复
+func useTestLease(t *testing.T) {
+	t.Helper()
+	saved := []interface{}{LeaseName, LeaseNamespace, LeaseDuration, RenewDeadline, RetryPeriod, LeaderIdentity}
+	t.Cleanup(func() {
+		LeaseName = saved[0].(string)
+		LeaseNamespace = saved[1].(string)
+		LeaseDuration = saved[2].(time.Duration)
+		RenewDeadline = saved[3].(time.Duration)
+		RetryPeriod = saved[4].(time.Duration)
+		LeaderIdentity = saved[5].(string)
+	})
+
+	LeaseName = "controller-demo"
+	LeaseNamespace = "default"
+	LeaseDuration = 2 * time.Second
+	RenewDeadline = time.Second
+	RetryPeriod = 100 * time.Millisecond
+	LeaderIdentity = testIdentity
+}
+
+func leaseHolder(t *testing.T, clientset *fake.Clientset) string {
+	t.Helper()
+	lease, err := clientset.CoordinationV1().Leases(LeaseNamespace).Get(context.Background(), LeaseName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("get lease: %v", err)
+	}
+	return ptr.Deref(lease.Spec.HolderIdentity, "")
+}
+
+func TestLeaderElectionRunsAfterAcquireAndReleasesAfterRun(t *testing.T) {
+	useTestLease(t)
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	started := make(chan struct{})
+	holderWhileDraining := make(chan string, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- runWithLeaderElection(ctx, clientset, func(ctx context.Context) error {
+			if holder := leaseHolder(t, clientset); holder != testIdentity {
+				t.Errorf("run started while the lease is held by %q", holder)
+			}
+			close(started)
+			<-ctx.Done()
+			// 模拟 drain, 这段时间内 Lease 必须仍然由自己持有
+			time.Sleep(3 * RetryPeriod)
+			holderWhileDraining <- leaseHolder(t, clientset)
+			return nil
+		})
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("run was not started")
+	}
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("runWithLeaderElection() error = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("runWithLeaderElection did not return after ctx was cancelled")
+	}
+	if holder := <-holderWhileDraining; holder != testIdentity {
+		t.Errorf("lease holder while draining = %q, want %q", holder, testIdentity)
+	}
+	if holder := leaseHolder(t, clientset); holder != "" {
+		t.Errorf("lease holder after return = %q, want the lease to be released", holder)
+	}
+}
+
+func TestLeaderElectionDoesNotRunWithoutLease(t *testing.T) {
+	useTestLease(t)
+	now := metav1.NewMicroTime(time.Now())
+	clientset := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: LeaseName, Namespace: LeaseNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To("replica-b"),
+			LeaseDurationSeconds: ptr.To[int32](60),
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*RetryPeriod)
+	defer cancel()
+
+	err := runWithLeaderElection(ctx, clientset, func(ctx context.Context) error {
+		t.Error("run started while another replica holds the lease")
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runWithLeaderElection() error = %v, want nil", err)
+	}
+	if holder := leaseHolder(t, clientset); holder != "replica-b" {
+		t.Errorf("lease holder = %q, want replica-b", holder)
+	}
+}
+
+func TestLeaderElectionLostLease(t *testing.T) {
+	useTestLease(t)
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// 当选之后续约全部失败, reactor 需要在选主开始前注册, PrependReactor 不能和请求并发调用
+	var leading atomic.Bool
+	clientset.PrependReactor("update", "leases", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if !leading.Load() {
+			return false, nil, nil
+		}
+		return true, nil, errors.New("apiserver unavailable")
+	})
+
+	runCause := make(chan error, 1)
+	result := make(chan error, 1)
+	go func() {
+		result <- runWithLeaderElection(ctx, clientset, func(ctx context.Context) error {
+			leading.Store(true)
+			<-ctx.Done()
+			runCause <- context.Cause(ctx)
+			return nil
+		})
+	}()
+
+	select {
+	case err := <-result:
+		if err == nil || !strings.Contains(err.Error(), "lost leadership") {
+			t.Fatalf("runWithLeaderElection() error = %v, want lost leadership", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("runWithLeaderElection did not return after the lease was lost")
+	}
+	if cause := <-runCause; !errors.Is(cause, errLeadershipLost) {
+		t.Errorf("run ctx cause = %v, want %v", cause, errLeadershipLost)
+	}
+}
+
+func TestLeaderElectionReturnsRunError(t *testing.T) {
+	useTestLease(t)
+	clientset := fake.NewSimpleClientset()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	runErr := errors.New("informer cache did not sync")
+	err := runWithLeaderElection(ctx, clientset, func(ctx context.Context) error {
+		return runErr
+	})
+	// run 返回之后释放 Lease 不算失去 Lease
+	if !errors.Is(err, runErr) {
+		t.Fatalf("runWithLeaderElection() error = %v, want %v", err, runErr)
+	}
+	if holder := leaseHolder(t, clientset); holder != "" {
+		t.Errorf("lease holder after return = %q, want the lease to be released", holder)
+	}
+}
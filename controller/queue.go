@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+// trackingQueue 在限速队列外面记录每个 key 的状态, workqueue 本身不提供查看队列内容的接口
+// 它也自己记录每个 key 的重试次数, BucketRateLimiter 的 NumRequeues 总是返回 0
+type trackingQueue struct {
+	workqueue.RateLimitingInterface
+	rateLimiter workqueue.RateLimiter
+
+	mu         sync.Mutex
+	queued     map[interface{}]time.Time // 等待被 worker 取出
+	delayed    map[interface{}]time.Time // 通过 AddAfter 延迟入队, 值是预计入队的时间
+	processing map[interface{}]time.Time // 正在被 worker 处理, 值是开始处理的时间
+	requeues   map[interface{}]int
+}
+
+func newTrackingQueue(rateLimiter workqueue.RateLimiter, name string) *trackingQueue {
+	return &trackingQueue{
+		RateLimitingInterface: workqueue.NewRateLimitingQueueWithConfig(rateLimiter, workqueue.RateLimitingQueueConfig{Name: name}),
+		rateLimiter:           rateLimiter,
+		queued:                map[interface{}]time.Time{},
+		delayed:               map[interface{}]time.Time{},
+		processing:            map[interface{}]time.Time{},
+		requeues:              map[interface{}]int{},
+	}
+}
+
+func (q *trackingQueue) Add(item interface{}) {
+	q.mu.Lock()
+	if _, ok := q.queued[item]; !ok && !q.ShuttingDown() {
+		q.queued[item] = time.Now()
+	}
+	q.mu.Unlock()
+	q.RateLimitingInterface.Add(item)
+}
+
+func (q *trackingQueue) AddAfter(item interface{}, duration time.Duration) {
+	if duration <= 0 {
+		q.Add(item)
+		return
+	}
+	q.mu.Lock()
+	// 同一个 key 多次延迟入队时 workqueue 以最早的时间为准
+	readyAt := time.Now().Add(duration)
+	if current, ok := q.delayed[item]; !q.ShuttingDown() && (!ok || readyAt.Before(current)) {
+		q.delayed[item] = readyAt
+	}
+	q.mu.Unlock()
+	q.RateLimitingInterface.AddAfter(item, duration)
+}
+
+// AddRateLimited 和 workqueue 的实现一样按限速器给出的时间延迟入队, 这样才能记录下延迟的时间
+func (q *trackingQueue) AddRateLimited(item interface{}) {
+	q.mu.Lock()
+	q.requeues[item]++
+	q.mu.Unlock()
+	q.AddAfter(item, q.rateLimiter.When(item))
+}
+
+func (q *trackingQueue) NumRequeues(item interface{}) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.requeues[item]
+}
+
+func (q *trackingQueue) Forget(item interface{}) {
+	q.mu.Lock()
+	delete(q.requeues, item)
+	q.mu.Unlock()
+	q.RateLimitingInterface.Forget(item)
+}
+
+func (q *trackingQueue) Get() (interface{}, bool) {
+	item, quit := q.RateLimitingInterface.Get()
+	if quit {
+		return item, quit
+	}
+	q.mu.Lock()
+	delete(q.queued, item)
+	delete(q.delayed, item)
+	q.processing[item] = time.Now()
+	q.mu.Unlock()
+	return item, quit
+}
+
+func (q *trackingQueue) Done(item interface{}) {
+	q.mu.Lock()
+	delete(q.processing, item)
+	q.mu.Unlock()
+	q.RateLimitingInterface.Done(item)
+}
+
+// QueueItem 是 /debug 中队列里的一个 key
+type QueueItem struct {
+	Key string `json:"key"`
+	// queued 是入队时间, delayed 是预计入队时间, processing 是开始处理的时间
+	Since    time.Time `json:"since"`
+	Requeues int       `json:"requeues,omitempty"`
+}
+
+// QueueSnapshot 是 /debug 输出的队列内容
+type QueueSnapshot struct {
+	Length     int         `json:"length"`
+	Queued     []QueueItem `json:"queued"`
+	Delayed    []QueueItem `json:"delayed"`
+	Processing []QueueItem `json:"processing"`
+}
+
+func (q *trackingQueue) Snapshot() QueueSnapshot {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// 延迟时间到了之后 workqueue 直接把 key 放入内部队列, 不会经过 Add, 这里把它们移到 queued 中
+	now := time.Now()
+	for item, readyAt := range q.delayed {
+		if readyAt.After(now) {
+			continue
+		}
+		delete(q.delayed, item)
+		if _, ok := q.queued[item]; !ok {
+			q.queued[item] = readyAt
+		}
+	}
+
+	return QueueSnapshot{
+		Length:     q.Len(),
+		Queued:     q.items(q.queued),
+		Delayed:    q.items(q.delayed),
+		Processing: q.items(q.processing),
+	}
+}
+
+func (q *trackingQueue) items(m map[interface{}]time.Time) []QueueItem {
+	items := make([]QueueItem, 0, len(m))
+	for item, since := range m {
+		key, _ := item.(string)
+		items = append(items, QueueItem{Key: key, Since: since, Requeues: q.requeues[item]})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Since.Before(items[j].Since) })
+	return items
+}
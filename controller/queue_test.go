@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/client-go/util/workqueue"
+)
+
+func snapshotKeys(items []QueueItem) []string {
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.Key)
+	}
+	return keys
+}
+
+func TestSnapshotMovesReadyDelayedItemsToQueued(t *testing.T) {
+	q := newTrackingQueue(workqueue.DefaultControllerRateLimiter(), "")
+	defer q.ShutDown()
+
+	q.AddAfter("default/ready", 10*time.Millisecond)
+	q.AddAfter("default/later", time.Hour)
+
+	snapshot := q.Snapshot()
+	if got := snapshotKeys(snapshot.Delayed); len(got) != 2 {
+		t.Fatalf("delayed = %v, want both keys", got)
+	}
+
+	// 等到 workqueue 把 key 放入内部队列
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	snapshot = q.Snapshot()
+	if got := snapshotKeys(snapshot.Queued); len(got) != 1 || got[0] != "default/ready" {
+		t.Errorf("queued = %v, want [default/ready]", got)
+	}
+	if got := snapshotKeys(snapshot.Delayed); len(got) != 1 || got[0] != "default/later" {
+		t.Errorf("delayed = %v, want [default/later]", got)
+	}
+
+	item, _ := q.Get()
+	snapshot = q.Snapshot()
+	if item != "default/ready" || len(snapshot.Queued) != 0 || len(snapshot.Processing) != 1 {
+		t.Errorf("after Get(%v): queued = %v, processing = %v", item, snapshotKeys(snapshot.Queued), snapshotKeys(snapshot.Processing))
+	}
+}
+
+func TestAddAfterKeepsEarliestTime(t *testing.T) {
+	q := newTrackingQueue(workqueue.DefaultControllerRateLimiter(), "")
+	defer q.ShutDown()
+
+	q.AddAfter("default/web", time.Minute)
+	q.AddAfter("default/web", time.Hour)
+
+	delayed := q.Snapshot().Delayed
+	if len(delayed) != 1 || time.Until(delayed[0].Since) > 2*time.Minute {
+		t.Errorf("delayed = %+v, want one item ready within a minute", delayed)
+	}
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+)
+
+// Result 告诉 Controller 处理成功后是否需要再次处理这个 key
+type Result struct {
+	// 为 true 时按限速队列的退避时间重新入队
+	Requeue bool
+	// 大于 0 时在指定时间之后重新入队, 优先于 Requeue
+	RequeueAfter time.Duration
+}
+
+// Reconciler 是 Controller 的业务逻辑, 一般会比较 spec 和 status 的差异, 然后做出处理使得 status 与 spec 保持一致
+// key 的格式是 namespace/name, 对象已经被删除时本地存储中找不到这个 key
+type Reconciler interface {
+	Reconcile(ctx context.Context, key string) (Result, error)
+}
+
+// ReconcilerFunc 让普通函数实现 Reconciler
+type ReconcilerFunc func(ctx context.Context, key string) (Result, error)
+
+func (f ReconcilerFunc) Reconcile(ctx context.Context, key string) (Result, error) {
+	return f(ctx, key)
+}
+
+// StoreInjector 由需要读取本地存储的 Reconciler 实现, Controller 创建好 informer 之后会注入它的本地存储
+type StoreInjector[T runtime.Object] interface {
+	InjectStore(store Store[T])
+}
+
+// Store 是 informer 本地存储的类型安全封装
+type Store[T runtime.Object] struct {
+	indexer cache.Indexer
+}
+
+func NewStore[T runtime.Object](indexer cache.Indexer) Store[T] {
+	return Store[T]{indexer: indexer}
+}
+
+// Get 根据 namespace/name 从本地存储中获取对象, 对象不存在时 exists 为 false
+func (s Store[T]) Get(key string) (obj T, exists bool, err error) {
+	item, exists, err := s.indexer.GetByKey(key)
+	if err != nil || !exists {
+		return obj, exists, err
+	}
+	obj, ok := item.(T)
+	if !ok {
+		return obj, false, fmt.Errorf("object with key %s has unexpected type %T", key, item)
+	}
+	return obj, true, nil
+}
+
+// List 返回本地存储中的所有对象
+func (s Store[T]) List() []T {
+	items := s.indexer.List()
+	objs := make([]T, 0, len(items))
+	for _, item := range items {
+		if obj, ok := item.(T); ok {
+			objs = append(objs, obj)
+		}
+	}
+	return objs
+}
+
+// StdoutReconciler 只打印一行日志并发送一个 Synced 事件, 用来演示 Reconciler 的写法
+type StdoutReconciler[T runtime.Object] struct {
+	// 日志中使用的资源类型, 例如 Pod
+	Kind string
+	// 不为 nil 时对象第一次被处理时加上 finalizer, 删除时执行清理
+	Finalizer *Finalizer[T]
+	store     Store[T]
+	recorder  record.EventRecorder
+}
+
+func NewStdoutReconciler[T runtime.Object](kind string) *StdoutReconciler[T] {
+	return &StdoutReconciler[T]{Kind: kind}
+}
+
+func (r *StdoutReconciler[T]) InjectStore(store Store[T]) {
+	r.store = store
+}
+
+func (r *StdoutReconciler[T]) InjectRecorder(recorder record.EventRecorder) {
+	r.recorder = recorder
+}
+
+func (r *StdoutReconciler[T]) Reconcile(ctx context.Context, key string) (Result, error) {
+	// 根据key从本地存储中获取对象信息, 因为有长连接和apiserver保持同步, 因此本地的信息是和集群一致的
+	obj, exists, err := r.store.Get(key)
+	if err != nil {
+		return Result{}, fmt.Errorf("fetching object with key %s from store failed: %w", key, err)
+	}
+
+	if !exists {
+		log.Printf("%s %s does not exist anymore\n", r.Kind, key)
+		return Result{}, nil
+	}
+
+	// 此处为了代码简单仅仅打印一行日志, 并在对象上发送一个事件
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return Result{}, err
+	}
+	if r.Finalizer != nil {
+		deleting, err := r.Finalizer.Handle(ctx, obj)
+		if err != nil {
+			return Result{}, err
+		}
+		if deleting {
+			log.Printf("%s %s is being deleted\n", r.Kind, accessor.GetName())
+			return Result{}, nil
+		}
+	}
+	log.Printf("Sync/Add/Update for %s %s\n", r.Kind, accessor.GetName())
+	if r.recorder != nil {
+		r.recorder.Eventf(obj, v1.EventTypeNormal, "Synced", "%s synced successfully", r.Kind)
+	}
+	return Result{}, nil
+}
@@ -0,0 +1,166 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+	"github.com/xlcbingo1999/example-client-go/probe"
+)
+
+// 限速器的类型
+const (
+	// 每个 key 单独指数退避
+	RateLimiterExponential = "exponential"
+	// 所有 key 共享一个令牌桶
+	RateLimiterBucket = "bucket"
+	// 取两者中较长的等待时间, 和 workqueue.DefaultControllerRateLimiter 一致
+	RateLimiterMaxOf = "max-of"
+)
+
+// RateLimiterConfig 描述工作队列的限速器
+type RateLimiterConfig struct {
+	Kind string
+	// 指数退避的初始和最大等待时间
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// 令牌桶每秒产生的令牌数和桶的容量
+	QPS   float64
+	Burst int
+}
+
+// NewRateLimiter 根据配置创建限速器, 不认识的类型返回 UsageError
+func NewRateLimiter(config RateLimiterConfig) (workqueue.RateLimiter, error) {
+	exponential := func() workqueue.RateLimiter {
+		return workqueue.NewItemExponentialFailureRateLimiter(config.BaseDelay, config.MaxDelay)
+	}
+	bucket := func() workqueue.RateLimiter {
+		return &workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(config.QPS), config.Burst)}
+	}
+
+	switch config.Kind {
+	case RateLimiterExponential:
+		return exponential(), nil
+	case RateLimiterBucket:
+		return bucket(), nil
+	case RateLimiterMaxOf:
+		return workqueue.NewMaxOfRateLimiter(exponential(), bucket()), nil
+	}
+	return nil, &exitcode.UsageError{Err: fmt.Errorf("unsupported rate limiter %q, must be %s, %s or %s",
+		config.Kind, RateLimiterExponential, RateLimiterBucket, RateLimiterMaxOf)}
+}
+
+// DeadLetter 记录超过最大重试次数后被丢弃的 key
+type DeadLetter struct {
+	Key       string    `json:"key"`
+	LastError string    `json:"lastError"`
+	Attempts  int       `json:"attempts"`
+	DroppedAt time.Time `json:"droppedAt"`
+}
+
+// deadLetters 只保留最近 limit 个被丢弃的 key, 同一个 key 再次被丢弃时更新记录
+type deadLetters struct {
+	mu      sync.Mutex
+	limit   int
+	order   []string
+	entries map[string]DeadLetter
+}
+
+func newDeadLetters(limit int) *deadLetters {
+	return &deadLetters{limit: limit, entries: map[string]DeadLetter{}}
+}
+
+func (d *deadLetters) add(letter DeadLetter) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.limit <= 0 {
+		return
+	}
+	d.removeLocked(letter.Key)
+	d.order = append(d.order, letter.Key)
+	d.entries[letter.Key] = letter
+	for len(d.order) > d.limit {
+		delete(d.entries, d.order[0])
+		d.order = d.order[1:]
+	}
+}
+
+// remove 删除 key 的记录, 返回之前是否存在
+func (d *deadLetters) remove(key string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.removeLocked(key)
+}
+
+func (d *deadLetters) removeLocked(key string) bool {
+	if _, ok := d.entries[key]; !ok {
+		return false
+	}
+	delete(d.entries, key)
+	for i, k := range d.order {
+		if k == key {
+			d.order = append(d.order[:i], d.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// list 按丢弃时间从旧到新返回所有记录
+func (d *deadLetters) list() []DeadLetter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(d.order))
+	for _, key := range d.order {
+		letters = append(letters, d.entries[key])
+	}
+	return letters
+}
+
+// DeadLetters 返回超过最大重试次数后被丢弃的 key
+func (c *Controller[T]) DeadLetters() []DeadLetter {
+	return c.deadLetters.list()
+}
+
+// Requeue 把被丢弃的 key 重新放入工作队列并重新计算重试次数, key 不在记录中时返回 false
+func (c *Controller[T]) Requeue(key string) bool {
+	if !c.deadLetters.remove(key) {
+		return false
+	}
+	c.queue.Forget(key)
+	c.queue.Add(key)
+	return true
+}
+
+// serveDeadLetters 处理 /debug/<name>/dead-letters:
+// GET 返回被丢弃的 key, POST ?key=namespace/name 重新入队一个 key, 不带 key 时全部重新入队
+func (c *Controller[T]) serveDeadLetters(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		probe.WriteJSON(w, c.DeadLetters())
+	case http.MethodPost:
+		keys := r.URL.Query()["key"]
+		if len(keys) == 0 {
+			for _, letter := range c.DeadLetters() {
+				keys = append(keys, letter.Key)
+			}
+		}
+		requeued := []string{}
+		for _, key := range keys {
+			if c.Requeue(key) {
+				requeued = append(requeued, key)
+			}
+		}
+		probe.WriteJSON(w, map[string][]string{"requeued": requeued})
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// DrainError 表示退出时正在处理的 key 没能在期限内完成, 它们的 Reconcile 被强制取消
+type DrainError struct {
+	Controller string
+	Timeout    time.Duration
+	InFlight   int
+}
+
+func (e *DrainError) Error() string {
+	return fmt.Sprintf("%s controller did not drain within %v, %d key(s) still in flight", e.Controller, e.Timeout, e.InFlight)
+}
+
+func (e *DrainError) Reason() string { return "DrainTimeout" }
+
+func (e *DrainError) ExitCode() int { return exitcode.ShutdownDirty }
+
+// drain 停止接受新的 key 并等待 workers 退出, 超过 drainTimeout 后取消正在执行的 Reconcile
+func (c *Controller[T]) drain(workersDone *sync.WaitGroup, cancelWorkers context.CancelFunc) error {
+	c.stopping.Store(true)
+
+	drained := make(chan struct{})
+	go func() {
+		// 不再接受新的 key, 阻塞直到已经取出的 key 都调用了 Done
+		c.queue.ShutDownWithDrain()
+		workersDone.Wait()
+		close(drained)
+	}()
+
+	timer := time.NewTimer(c.drainTimeout)
+	defer timer.Stop()
+	select {
+	case <-drained:
+		klog.Infof("%s controller drained", c.name)
+		return nil
+	case <-timer.C:
+	}
+
+	inFlight := int(c.inFlight.Load())
+	cancelWorkers()
+	// 解除 ShutDownWithDrain 的阻塞
+	c.queue.ShutDown()
+	return &DrainError{Controller: c.name, Timeout: c.drainTimeout, InFlight: inFlight}
+}
+
+// abort 不等待正在处理的 key, 直接取消它们的 Reconcile
+// 用于失去 Lease 之后, 这时其它副本可能已经开始处理同样的 key
+func (c *Controller[T]) abort(cancelWorkers context.CancelFunc) {
+	c.stopping.Store(true)
+	klog.Warningf("%s controller lost leadership, cancelling %d in-flight key(s)", c.name, c.inFlight.Load())
+	cancelWorkers()
+	c.queue.ShutDown()
+}
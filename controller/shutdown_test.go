@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// podListWatchFor 直接使用 clientset 的 List/Watch, fake clientset 没有 RESTClient
+func podListWatchFor(clientset kubernetes.Interface) cache.ListerWatcher {
+	return &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Pods(v1.NamespaceAll).List(context.Background(), options)
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Pods(v1.NamespaceAll).Watch(context.Background(), options)
+		},
+	}
+}
+
+// blockingReconciler 一直阻塞到 ctx 被取消
+func blockingReconciler(started chan<- struct{}, cancelled chan<- struct{}) Reconciler {
+	return ReconcilerFunc(func(ctx context.Context, key string) (Result, error) {
+		close(started)
+		<-ctx.Done()
+		close(cancelled)
+		return Result{}, ctx.Err()
+	})
+}
+
+func runBlockedController(t *testing.T, drainTimeout time.Duration, stop func(cancel context.CancelCauseFunc)) (time.Duration, error) {
+	t.Helper()
+	clientset := fake.NewSimpleClientset(&v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"}})
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	c := NewController[*v1.Pod]("shutdown-test", podListWatchFor(clientset), blockingReconciler(started, cancelled),
+		WithDrainTimeout(drainTimeout))
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	result := make(chan error, 1)
+	go func() { result <- c.Run(ctx, 1) }()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("reconcile was not started")
+	}
+	begin := time.Now()
+	stop(cancel)
+
+	select {
+	case err := <-result:
+		<-cancelled
+		return time.Since(begin), err
+	case <-time.After(drainTimeout + 5*time.Second):
+		t.Fatal("Run did not return")
+	}
+	return 0, nil
+}
+
+func TestRunReturnsDrainErrorAfterTimeout(t *testing.T) {
+	_, err := runBlockedController(t, 200*time.Millisecond, func(cancel context.CancelCauseFunc) { cancel(nil) })
+	var drainErr *DrainError
+	if !errors.As(err, &drainErr) || drainErr.InFlight != 1 {
+		t.Fatalf("Run() error = %v, want DrainError with 1 key in flight", err)
+	}
+}
+
+func TestRunAbortsWithoutDrainWhenLeadershipLost(t *testing.T) {
+	elapsed, err := runBlockedController(t, time.Minute, func(cancel context.CancelCauseFunc) { cancel(errLeadershipLost) })
+	if err != nil {
+		t.Fatalf("Run() error = %v, want nil", err)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("Run returned after %v, want it not to wait for the drain timeout", elapsed)
+	}
+}
@@ -0,0 +1,109 @@
+package controller
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ownerReferences 最多向上查找的层数, 避免错误的引用形成环
+const maxOwnerDepth = 5
+
+// MapFunc 把次要资源的对象映射成需要处理的主资源的 key(namespace/name)
+type MapFunc func(obj interface{}) []string
+
+// Watches 让 Controller 同时监听次要资源, 次要资源变化时把 mapFunc 返回的主资源 key 放入工作队列
+// informer 会和主资源的 informer 一起在 Run 中启动并等待同步
+func (c *Controller[T]) Watches(informer cache.SharedIndexInformer, mapFunc MapFunc) error {
+	enqueue := func(obj interface{}) {
+		// 删除事件可能拿到的是 DeletedFinalStateUnknown
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		for _, key := range mapFunc(obj) {
+			c.queue.Add(key)
+		}
+	}
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: enqueue,
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// owner 或标签发生变化时, 旧的主资源也需要重新处理
+			enqueue(oldObj)
+			enqueue(newObj)
+		},
+		DeleteFunc: enqueue,
+	})
+	if err != nil {
+		return fmt.Errorf("add event handler to secondary informer of %s: %w", c.name, err)
+	}
+	c.secondaries = append(c.secondaries, informer)
+	return nil
+}
+
+// informers 返回主资源和所有次要资源的 informer
+func (c *Controller[T]) informers() []cache.SharedIndexInformer {
+	return append([]cache.SharedIndexInformer{c.informer}, c.secondaries...)
+}
+
+func (c *Controller[T]) hasSynced() bool {
+	for _, informer := range c.informers() {
+		if !informer.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// EnqueueOwner 沿着 controller ownerReference 向上查找类型为 owner 的对象, 例如 Pod -> ReplicaSet -> Deployment
+// 中间经过的对象需要在 intermediates 中提供对应类型的本地存储, 找不到时放弃
+func EnqueueOwner(owner schema.GroupKind, intermediates map[schema.GroupKind]cache.Store) MapFunc {
+	return func(obj interface{}) []string {
+		object, err := meta.Accessor(obj)
+		if err != nil {
+			utilruntime.HandleError(err)
+			return nil
+		}
+
+		for depth := 0; depth < maxOwnerDepth; depth++ {
+			ref := metav1.GetControllerOfNoCopy(object)
+			if ref == nil {
+				return nil
+			}
+			gv, err := schema.ParseGroupVersion(ref.APIVersion)
+			if err != nil {
+				return nil
+			}
+
+			// ownerReference 只能指向同一个 namespace 的对象
+			key := ref.Name
+			if object.GetNamespace() != "" {
+				key = object.GetNamespace() + "/" + ref.Name
+			}
+			kind := schema.GroupKind{Group: gv.Group, Kind: ref.Kind}
+			if kind == owner {
+				return []string{key}
+			}
+
+			store, ok := intermediates[kind]
+			if !ok {
+				return nil
+			}
+			item, exists, err := store.GetByKey(key)
+			if err != nil || !exists {
+				return nil
+			}
+			next, err := meta.Accessor(item)
+			// 同名的新对象不是原来的 owner
+			if err != nil || next.GetUID() != ref.UID {
+				return nil
+			}
+			object = next
+		}
+		return nil
+	}
+}
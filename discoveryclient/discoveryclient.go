@@ -1,40 +1,29 @@
 package discoveryclient
 
 import (
+	"fmt"
 	"log"
-	"path/filepath"
 
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/discovery"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
-)
 
-var (
-	Kubeconfig string
+	"github.com/xlcbingo1999/example-client-go/connection"
 )
 
-func RunDiscoveryClient() {
-	// home是家目录，如果能取得家目录的值，就可以用来做默认值
-	if home := homedir.HomeDir(); home != "" {
-		// 如果输入了kubeconfig参数，该参数的值就是kubeconfig文件的绝对路径，
-		// 如果没有输入kubeconfig参数，就用默认路径~/.kube/config
-		Kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-
-	config, err := clientcmd.BuildConfigFromFlags("", Kubeconfig)
+func RunDiscoveryClient() error {
+	config, err := connection.RESTConfig()
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	APIGroup, APIResourceListSlice, err := discoveryClient.ServerGroupsAndResources()
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("discover server groups and resources: %w", err)
 	}
 
 	log.Printf("APIGroup :\n\n %v\n\n\n\n", APIGroup)
@@ -49,7 +38,7 @@ func RunDiscoveryClient() {
 		gv, err := schema.ParseGroupVersion(groupVerionStr)
 
 		if err != nil {
-			panic(err.Error())
+			return err
 		}
 
 		log.Println("*****************************************************************")
@@ -60,4 +49,5 @@ func RunDiscoveryClient() {
 			log.Printf("resources : %v\n", singleAPIResource.Name)
 		}
 	}
+	return nil
 }
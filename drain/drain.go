@@ -0,0 +1,245 @@
+package drain
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/ptr"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
+)
+
+// drain 命令的参数
+var (
+	// 为 true 时允许驱逐使用 emptyDir 的 pod, 这些 pod 的本地数据会丢失
+	DeleteEmptyDirData bool
+	// 驱逐时 pod 的优雅退出时间, 小于 0 时使用 pod 自己的设置
+	GracePeriod int64
+	// 整个 drain 过程的超时时间, 0 表示一直等待
+	Timeout time.Duration
+	// 检查被驱逐的 pod 是否已经删除的间隔
+	PollInterval = 2 * time.Second
+)
+
+// 驱逐被 PodDisruptionBudget 拒绝(429)后的重试间隔
+var evictionBackoff = wait.Backoff{
+	Duration: time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Cap:      30 * time.Second,
+	Steps:    math.MaxInt32,
+}
+
+func RunDrain(node string) error {
+	clientset, err := connection.NewClientSet()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+	if Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, Timeout)
+		defer cancel()
+	}
+
+	if err := cordon(ctx, clientset, node, true); err != nil {
+		return err
+	}
+	return drain(ctx, clientset, node)
+}
+
+func RunUncordon(node string) error {
+	clientset, err := connection.NewClientSet()
+	if err != nil {
+		return err
+	}
+	return cordon(context.TODO(), clientset, node, false)
+}
+
+// cordon 设置节点是否可调度, 已经是目标状态时什么都不做
+func cordon(ctx context.Context, clientset kubernetes.Interface, name string, unschedulable bool) error {
+	node, err := clientset.CoreV1().Nodes().Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("get node %s: %w", name, err)
+	}
+
+	action := "cordoned"
+	if !unschedulable {
+		action = "uncordoned"
+	}
+	if node.Spec.Unschedulable == unschedulable {
+		log.Printf("Node %s already %s\n", name, action)
+		return nil
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err = clientset.CoreV1().Nodes().Patch(ctx, name, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("patch node %s: %w", name, err)
+	}
+	log.Printf("Node %s %s\n", name, action)
+	return nil
+}
+
+// drain 驱逐节点上的 pod 并等待它们被删除
+func drain(ctx context.Context, clientset kubernetes.Interface, node string) error {
+	pods, err := podsToEvict(ctx, clientset, node)
+	if err != nil {
+		return err
+	}
+	if len(pods) == 0 {
+		log.Printf("Node %s drained, no pods to evict\n", node)
+		return nil
+	}
+	log.Printf("Evicting %d pod(s) from node %s\n", len(pods), node)
+
+	var (
+		mu    sync.Mutex
+		done  int
+		errs  []error
+		group sync.WaitGroup
+	)
+	for i := range pods {
+		group.Add(1)
+		go func(pod *apiv1.Pod) {
+			defer group.Done()
+			err := evictAndWait(ctx, clientset, pod)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			done++
+			log.Printf("Pod %s/%s evicted (%d/%d)\n", pod.Namespace, pod.Name, done, len(pods))
+		}(&pods[i])
+	}
+	group.Wait()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("drain node %s: %w", node, utilerrors.NewAggregate(errs))
+	}
+	log.Printf("Node %s drained\n", node)
+	return nil
+}
+
+// podsToEvict 返回需要驱逐的 pod, 跳过 DaemonSet 和 mirror pod
+// 使用 emptyDir 的 pod 在没有 DeleteEmptyDirData 时会让 drain 直接失败
+func podsToEvict(ctx context.Context, clientset kubernetes.Interface, node string) ([]apiv1.Pod, error) {
+	list, err := clientset.CoreV1().Pods(apiv1.NamespaceAll).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", node).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list pods on node %s: %w", node, err)
+	}
+
+	var pods []apiv1.Pod
+	var localStorage []string
+	for _, pod := range list.Items {
+		// 不是所有 clientset 实现都支持 field selector, 这里再过滤一次
+		if pod.Spec.NodeName != node {
+			continue
+		}
+		if _, ok := pod.Annotations[apiv1.MirrorPodAnnotationKey]; ok {
+			log.Printf("Skipping mirror pod %s/%s\n", pod.Namespace, pod.Name)
+			continue
+		}
+		if ref := metav1.GetControllerOf(&pod); ref != nil && ref.Kind == "DaemonSet" {
+			log.Printf("Skipping DaemonSet-managed pod %s/%s\n", pod.Namespace, pod.Name)
+			continue
+		}
+		// 已经结束的 pod 没有需要保留的本地数据
+		if hasEmptyDir(&pod) && !DeleteEmptyDirData && !finished(&pod) {
+			localStorage = append(localStorage, pod.Namespace+"/"+pod.Name)
+			continue
+		}
+		pods = append(pods, pod)
+	}
+
+	if len(localStorage) > 0 {
+		return nil, fmt.Errorf("cannot drain node %s, pods with local storage (use --delete-emptydir-data to override): %s",
+			node, strings.Join(localStorage, ", "))
+	}
+	return pods, nil
+}
+
+func hasEmptyDir(pod *apiv1.Pod) bool {
+	for _, v := range pod.Spec.Volumes {
+		if v.EmptyDir != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func finished(pod *apiv1.Pod) bool {
+	return pod.Status.Phase == apiv1.PodSucceeded || pod.Status.Phase == apiv1.PodFailed
+}
+
+// evictAndWait 通过 Eviction API 驱逐 pod, 被 PodDisruptionBudget 拒绝时退避重试, 然后等待 pod 被删除
+func evictAndWait(ctx context.Context, clientset kubernetes.Interface, pod *apiv1.Pod) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+	}
+	if GracePeriod >= 0 {
+		eviction.DeleteOptions = &metav1.DeleteOptions{GracePeriodSeconds: ptr.To(GracePeriod)}
+	}
+
+	backoff := evictionBackoff
+	for {
+		err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		if err == nil || apierrors.IsNotFound(err) {
+			break
+		}
+		if !apierrors.IsTooManyRequests(err) {
+			return fmt.Errorf("evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+		}
+
+		// apiserver 可能通过 Retry-After 给出建议的等待时间
+		delay := backoff.Step()
+		if seconds, ok := apierrors.SuggestsClientDelay(err); ok && seconds > 0 {
+			delay = time.Duration(seconds) * time.Second
+		}
+		log.Printf("Eviction of pod %s/%s refused, retrying in %s: %v\n", pod.Namespace, pod.Name, delay.Round(time.Second), err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("evict pod %s/%s: %w", pod.Namespace, pod.Name, ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+
+	// 同名的新 pod 的 UID 不同, 说明原来的 pod 已经删除
+	err := wait.PollUntilContextCancel(ctx, PollInterval, true, func(ctx context.Context) (bool, error) {
+		current, err := clientset.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			return true, nil
+		}
+		if err != nil {
+			return false, err
+		}
+		return current.UID != pod.UID, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for pod %s/%s to be deleted: %w", pod.Namespace, pod.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,201 @@
+package drain
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/utils/ptr"
+)
+
+const testNode = "node1"
+
+// useFastRetries 缩短退避和轮询间隔, 测试结束后恢复
+func useFastRetries(t *testing.T) {
+	t.Helper()
+	savedBackoff, savedPoll, savedEmptyDir := evictionBackoff, PollInterval, DeleteEmptyDirData
+	t.Cleanup(func() {
+		evictionBackoff, PollInterval, DeleteEmptyDirData = savedBackoff, savedPoll, savedEmptyDir
+	})
+	evictionBackoff = wait.Backoff{Duration: 10 * time.Millisecond, Factor: 1, Steps: 100}
+	PollInterval = 10 * time.Millisecond
+}
+
+type podOption func(*apiv1.Pod)
+
+func newPod(name string, opts ...podOption) *apiv1.Pod {
+	pod := &apiv1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: types.UID("uid-" + name)},
+		Spec:       apiv1.PodSpec{NodeName: testNode},
+		Status:     apiv1.PodStatus{Phase: apiv1.PodRunning},
+	}
+	for _, opt := range opts {
+		opt(pod)
+	}
+	return pod
+}
+
+func onNode(node string) podOption {
+	return func(pod *apiv1.Pod) { pod.Spec.NodeName = node }
+}
+
+func ownedBy(kind string) podOption {
+	return func(pod *apiv1.Pod) {
+		pod.OwnerReferences = []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: kind, Name: "owner", UID: "owner", Controller: ptr.To(true)}}
+	}
+}
+
+func mirror() podOption {
+	return func(pod *apiv1.Pod) {
+		pod.Annotations = map[string]string{apiv1.MirrorPodAnnotationKey: "hash"}
+	}
+}
+
+func withEmptyDir() podOption {
+	return func(pod *apiv1.Pod) {
+		pod.Spec.Volumes = []apiv1.Volume{{Name: "cache", VolumeSource: apiv1.VolumeSource{EmptyDir: &apiv1.EmptyDirVolumeSource{}}}}
+	}
+}
+
+func inPhase(phase apiv1.PodPhase) podOption {
+	return func(pod *apiv1.Pod) { pod.Status.Phase = phase }
+}
+
+func podNames(pods []apiv1.Pod) []string {
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// evictionDeletes 让驱逐真正删除 pod, fake clientset 默认只把 eviction 当作一次写入
+// 前 refusals 次驱逐返回 429, 返回值记录驱逐请求的次数
+func evictionDeletes(clientset *fake.Clientset, refusals int) *int {
+	evictions := 0
+	clientset.PrependReactor("create", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		evictions++
+		if evictions <= refusals {
+			return true, nil, apierrors.NewTooManyRequests("Cannot evict pod as it would violate the pod's disruption budget.", 0)
+		}
+		create := action.(k8stesting.CreateAction)
+		name := create.GetObject().(metav1.Object).GetName()
+		err := clientset.Tracker().Delete(action.GetResource(), action.GetNamespace(), name)
+		return true, nil, err
+	})
+	return &evictions
+}
+
+func TestPodsToEvictSkipsDaemonSetAndMirrorPods(t *testing.T) {
+	useFastRetries(t)
+	clientset := fake.NewSimpleClientset(
+		newPod("web"),
+		newPod("rs-owned", ownedBy("ReplicaSet")),
+		newPod("fluentd", ownedBy("DaemonSet")),
+		newPod("kube-proxy", mirror()),
+		newPod("elsewhere", onNode("node2")),
+	)
+
+	pods, err := podsToEvict(context.Background(), clientset, testNode)
+	if err != nil {
+		t.Fatalf("podsToEvict() error = %v", err)
+	}
+	if got, want := podNames(pods), []string{"rs-owned", "web"}; !slices.Equal(got, want) {
+		t.Errorf("pods to evict = %v, want %v", got, want)
+	}
+}
+
+func TestPodsToEvictEmptyDir(t *testing.T) {
+	useFastRetries(t)
+	clientset := fake.NewSimpleClientset(
+		newPod("web"),
+		newPod("cache", withEmptyDir()),
+		newPod("done", withEmptyDir(), inPhase(apiv1.PodSucceeded)),
+	)
+
+	_, err := podsToEvict(context.Background(), clientset, testNode)
+	if err == nil || !strings.Contains(err.Error(), "default/cache") {
+		t.Fatalf("podsToEvict() error = %v, want local storage error naming default/cache", err)
+	}
+	if strings.Contains(err.Error(), "default/done") {
+		t.Errorf("finished pod reported as having local storage: %v", err)
+	}
+
+	DeleteEmptyDirData = true
+	pods, err := podsToEvict(context.Background(), clientset, testNode)
+	if err != nil {
+		t.Fatalf("podsToEvict() with --delete-emptydir-data error = %v", err)
+	}
+	if got, want := podNames(pods), []string{"cache", "done", "web"}; !slices.Equal(got, want) {
+		t.Errorf("pods to evict = %v, want %v", got, want)
+	}
+}
+
+func TestDrainEvictsPods(t *testing.T) {
+	useFastRetries(t)
+	clientset := fake.NewSimpleClientset(
+		newPod("web-1"),
+		newPod("web-2"),
+		newPod("fluentd", ownedBy("DaemonSet")),
+	)
+	evictions := evictionDeletes(clientset, 0)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := drain(ctx, clientset, testNode); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+	if *evictions != 2 {
+		t.Errorf("evictions = %d, want 2", *evictions)
+	}
+	list, err := clientset.CoreV1().Pods("default").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("list pods: %v", err)
+	}
+	if got, want := podNames(list.Items), []string{"fluentd"}; !slices.Equal(got, want) {
+		t.Errorf("remaining pods = %v, want %v", got, want)
+	}
+}
+
+func TestDrainRetriesTooManyRequests(t *testing.T) {
+	useFastRetries(t)
+	clientset := fake.NewSimpleClientset(newPod("web"))
+	evictions := evictionDeletes(clientset, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := drain(ctx, clientset, testNode); err != nil {
+		t.Fatalf("drain() error = %v", err)
+	}
+	if *evictions != 3 {
+		t.Errorf("evictions = %d, want 3 (two refused by the disruption budget)", *evictions)
+	}
+}
+
+func TestDrainStopsWhenContextExpires(t *testing.T) {
+	useFastRetries(t)
+	clientset := fake.NewSimpleClientset(newPod("web"))
+	// 一直被 PodDisruptionBudget 拒绝
+	evictionDeletes(clientset, 1<<30)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	err := drain(ctx, clientset, testNode)
+	if err == nil || !strings.Contains(err.Error(), "default/web") {
+		t.Fatalf("drain() error = %v, want an error for default/web", err)
+	}
+}
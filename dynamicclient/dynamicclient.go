@@ -3,8 +3,8 @@ package dynamicclient
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
-	"path/filepath"
 
 	apiv1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/meta"
@@ -20,12 +20,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
 	"k8s.io/client-go/dynamic"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
 )
 
 var (
-	Kubeconfig      string
 	decUnstructured = yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 )
 
@@ -49,10 +48,10 @@ spec:
         image: nginx:1.24
 `
 
-func listAllPods(config *restclient.Config) {
+func listAllPods(config *restclient.Config, namespace string) error {
 	dynamicClient, err := dynamic.NewForConfig(config)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	gvr := schema.GroupVersionResource{
@@ -61,15 +60,15 @@ func listAllPods(config *restclient.Config) {
 	// 这里是可以根据 $group/$version/namespaces/$namespace/$resouce 去获取对应格式的资源情况
 	// 例子: http://localhost:6443/apis/apps/v1/namespaces/default/deployments
 
-	unstructObj, err := dynamicClient.Resource(gvr).Namespace("default").List(context.TODO(), metav1.ListOptions{Limit: 100})
+	unstructObj, err := dynamicClient.Resource(gvr).Namespace(namespace).List(context.TODO(), metav1.ListOptions{Limit: 100})
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("list %s in %s: %w", gvr.Resource, namespace, err)
 	}
 
 	podList := &apiv1.PodList{}
 	err = runtime.DefaultUnstructuredConverter.FromUnstructured(unstructObj.UnstructuredContent(), podList)
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	// 表头
@@ -82,9 +81,10 @@ func listAllPods(config *restclient.Config) {
 			d.Status.Phase,
 			d.Name)
 	}
+	return nil
 }
 
-func createDeploymentBySSA(ctx context.Context, cfg *restclient.Config) error {
+func createDeploymentBySSA(ctx context.Context, cfg *restclient.Config, namespace string) error {
 	// 构建一个restMapper用于寻找GVR
 	dc, err := discovery.NewDiscoveryClientForConfig(cfg)
 	if err != nil {
@@ -104,6 +104,7 @@ func createDeploymentBySSA(ctx context.Context, cfg *restclient.Config) error {
 	if err != nil {
 		return err
 	}
+	obj.SetNamespace(namespace)
 
 	// 寻找GVK
 	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
@@ -134,24 +135,18 @@ func createDeploymentBySSA(ctx context.Context, cfg *restclient.Config) error {
 	return err
 }
 
-func RunDynamicClient() {
-	// home是家目录，如果能取得家目录的值，就可以用来做默认值
-	if home := homedir.HomeDir(); home != "" {
-		// 如果输入了kubeconfig参数，该参数的值就是kubeconfig文件的绝对路径，
-		// 如果没有输入kubeconfig参数，就用默认路径~/.kube/config
-		Kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-
-	config, err := clientcmd.BuildConfigFromFlags("", Kubeconfig)
+func RunDynamicClient() error {
+	config, err := connection.RESTConfig()
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
+	namespace := connection.NamespaceOr("default")
 
-	// listAllPods(config)
-	err = createDeploymentBySSA(context.TODO(), config)
+	// listAllPods(config, namespace)
+	err = createDeploymentBySSA(context.TODO(), config, namespace)
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("apply deployment: %w", err)
 	}
 
-	listAllPods(config)
+	return listAllPods(config, namespace)
 }
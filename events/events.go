@@ -0,0 +1,290 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	watchtools "k8s.io/client-go/tools/watch"
+
+	"github.com/xlcbingo1999/example-client-go/clientset"
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// events 命令的参数, 除 Watch/Output 外都会转换成服务端的 field selector
+var (
+	// involvedObject 的类型和名字, 例如 Deployment/tomcat-deployment
+	Kind string
+	Name string
+	// Normal 或 Warning
+	Type   string
+	Reason string
+
+	AllNamespaces bool
+	// 列出已有事件后继续 watch 新的事件
+	Watch bool
+	// table 或 json
+	Output string
+)
+
+// Entry 是聚合后的事件, 相同对象上类型、原因和消息都相同的事件合并成一条
+type Entry struct {
+	Namespace string    `json:"namespace"`
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	Type      string    `json:"type"`
+	Reason    string    `json:"reason"`
+	Message   string    `json:"message"`
+	Count     int32     `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+type entryKey struct {
+	namespace, kind, name, eventType, reason, message string
+}
+
+// aggregator 聚合事件, 同一个 Event 对象在 watch 中被更新时, 它的 count 已经包含了之前的次数,
+// 所以按对象记录已经计入的次数, 只累加增量
+type aggregator struct {
+	entries map[entryKey]*Entry
+	counted map[types.UID]int32
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{
+		entries: map[entryKey]*Entry{},
+		counted: map[types.UID]int32{},
+	}
+}
+
+func RunEvents() error {
+	switch Output {
+	case "table", "json":
+	default:
+		return &exitcode.UsageError{Err: fmt.Errorf("unsupported output format %q, must be table or json", Output)}
+	}
+	switch Type {
+	case "", apiv1.EventTypeNormal, apiv1.EventTypeWarning:
+	default:
+		return &exitcode.UsageError{Err: fmt.Errorf("unsupported event type %q, must be %s or %s", Type, apiv1.EventTypeNormal, apiv1.EventTypeWarning)}
+	}
+
+	cs, err := connection.NewClientSet()
+	if err != nil {
+		return err
+	}
+	// 默认查看 clientset_demo 管理的 namespace
+	namespace := connection.NamespaceOr(clientset.NAMESPACE)
+	if AllNamespaces {
+		namespace = apiv1.NamespaceAll
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return run(ctx, cs, namespace, os.Stdout)
+}
+
+func run(ctx context.Context, clientset kubernetes.Interface, namespace string, w io.Writer) error {
+	client := clientset.CoreV1().Events(namespace)
+	listOptions := metav1.ListOptions{FieldSelector: fieldSelector().String()}
+
+	list, err := client.List(ctx, listOptions)
+	if err != nil {
+		return fmt.Errorf("list events in %q: %w", namespace, err)
+	}
+
+	agg := newAggregator()
+	for i := range list.Items {
+		agg.add(&list.Items[i])
+	}
+	if err := printEntries(w, agg.sorted(), Output, AllNamespaces, true); err != nil {
+		return err
+	}
+	if !Watch {
+		return nil
+	}
+
+	// 从 list 的 resourceVersion 开始 watch, 连接断开后 RetryWatcher 会自动重连
+	watcher, err := watchtools.NewRetryWatcher(list.ResourceVersion, &cache.ListWatch{
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			options.FieldSelector = listOptions.FieldSelector
+			return client.Watch(ctx, options)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-watcher.Done():
+			return fmt.Errorf("watch events in %q stopped", namespace)
+		case e := <-watcher.ResultChan():
+			switch e.Type {
+			case watch.Added, watch.Modified:
+				entry := agg.add(e.Object.(*apiv1.Event))
+				if err := printEntries(w, []*Entry{entry}, Output, AllNamespaces, false); err != nil {
+					return err
+				}
+			case watch.Error:
+				return fmt.Errorf("watch events in %q: %v", namespace, e.Object)
+			}
+		}
+	}
+}
+
+// fieldSelector 把过滤参数转换成 field selector, 由 apiserver 完成过滤
+func fieldSelector() fields.Selector {
+	set := fields.Set{}
+	if Kind != "" {
+		set["involvedObject.kind"] = Kind
+	}
+	if Name != "" {
+		set["involvedObject.name"] = Name
+	}
+	if Type != "" {
+		set["type"] = Type
+	}
+	if Reason != "" {
+		set["reason"] = Reason
+	}
+	return fields.SelectorFromSet(set)
+}
+
+// add 把事件合并到对应的 Entry 中, 返回合并后的 Entry
+func (a *aggregator) add(event *apiv1.Event) *Entry {
+	key := entryKey{
+		namespace: event.InvolvedObject.Namespace,
+		kind:      event.InvolvedObject.Kind,
+		name:      event.InvolvedObject.Name,
+		eventType: event.Type,
+		reason:    event.Reason,
+		message:   event.Message,
+	}
+	if key.namespace == "" {
+		key.namespace = event.Namespace
+	}
+
+	first, last := timestampsOf(event)
+	entry, ok := a.entries[key]
+	if !ok {
+		entry = &Entry{
+			Namespace: key.namespace,
+			Kind:      key.kind,
+			Name:      key.name,
+			Type:      key.eventType,
+			Reason:    key.reason,
+			Message:   key.message,
+			FirstSeen: first,
+			LastSeen:  last,
+		}
+		a.entries[key] = entry
+	}
+
+	count := countOf(event)
+	entry.Count += count - a.counted[event.UID]
+	a.counted[event.UID] = count
+	if first.Before(entry.FirstSeen) {
+		entry.FirstSeen = first
+	}
+	if last.After(entry.LastSeen) {
+		entry.LastSeen = last
+	}
+	return entry
+}
+
+// countOf 返回事件发生的次数, 新的 events.k8s.io 风格的事件把次数记录在 series 中
+func countOf(event *apiv1.Event) int32 {
+	if event.Series != nil && event.Series.Count > 0 {
+		return event.Series.Count
+	}
+	if event.Count > 0 {
+		return event.Count
+	}
+	return 1
+}
+
+// timestampsOf 返回事件第一次和最后一次发生的时间, 依次回退到 eventTime 和创建时间
+func timestampsOf(event *apiv1.Event) (time.Time, time.Time) {
+	first := event.FirstTimestamp.Time
+	if first.IsZero() {
+		first = event.EventTime.Time
+	}
+	if first.IsZero() {
+		first = event.CreationTimestamp.Time
+	}
+
+	last := event.LastTimestamp.Time
+	if event.Series != nil && !event.Series.LastObservedTime.IsZero() {
+		last = event.Series.LastObservedTime.Time
+	}
+	if last.IsZero() {
+		last = first
+	}
+	return first, last
+}
+
+// sorted 按最后一次发生的时间从旧到新排序, 和 kubectl get events 一致
+func (a *aggregator) sorted() []*Entry {
+	sorted := make([]*Entry, 0, len(a.entries))
+	for _, entry := range a.entries {
+		sorted = append(sorted, entry)
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].LastSeen.Equal(sorted[j].LastSeen) {
+			return sorted[i].LastSeen.Before(sorted[j].LastSeen)
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// printEntries 以表格或每行一个 JSON 对象的格式输出, 方便 watch 时逐条追加
+func printEntries(w io.Writer, entries []*Entry, output string, withNamespace, header bool) error {
+	if output == "json" {
+		encoder := json.NewEncoder(w)
+		for _, entry := range entries {
+			if err := encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 3, ' ', 0)
+	if header {
+		if withNamespace {
+			fmt.Fprint(tw, "NAMESPACE\t")
+		}
+		fmt.Fprintln(tw, "LAST SEEN\tTYPE\tREASON\tOBJECT\tCOUNT\tMESSAGE")
+	}
+	for _, entry := range entries {
+		if withNamespace {
+			fmt.Fprintf(tw, "%s\t", entry.Namespace)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s/%s\t%d\t%s\n", duration.HumanDuration(time.Since(entry.LastSeen)),
+			entry.Type, entry.Reason, entry.Kind, entry.Name, entry.Count, entry.Message)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,136 @@
+package exitcode
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// 进程退出码, 脚本可以根据退出码区分不同的失败原因, 完整列表见 README
+const (
+	OK            = 0
+	Error         = 1 // 没有归类的其他错误
+	Usage         = 2 // 命令行参数错误
+	NotFound      = 3
+	AlreadyExists = 4
+	Forbidden     = 5
+	Conflict      = 6
+	Timeout       = 7
+	Unauthorized  = 8
+	Invalid       = 9
+	RolloutFailed = 10 // deployment 滚动更新失败, 例如 ProgressDeadlineExceeded 或镜像拉取失败
+	ShutdownDirty = 11 // 收到退出信号后没能在期限内处理完正在进行的工作
+)
+
+// Coder 由需要自己决定退出码的错误实现
+type Coder interface {
+	ExitCode() int
+}
+
+// Reasoner 由需要在错误摘要中给出原因的非 API 错误实现
+type Reasoner interface {
+	Reason() string
+}
+
+// Summary 是输出给脚本使用的错误摘要
+type Summary struct {
+	Error    string                `json:"error"`
+	Reason   metav1.StatusReason   `json:"reason,omitempty"`
+	Code     int32                 `json:"code,omitempty"` // apiserver 返回的 HTTP 状态码
+	Details  *metav1.StatusDetails `json:"details,omitempty"`
+	ExitCode int                   `json:"exitCode"`
+}
+
+// UsageError 表示命令行参数错误
+type UsageError struct {
+	Err error
+}
+
+func (e *UsageError) Error() string { return e.Err.Error() }
+
+func (e *UsageError) Unwrap() error { return e.Err }
+
+func (e *UsageError) ExitCode() int { return Usage }
+
+// ForError 根据错误类型返回对应的退出码, 支持被 fmt.Errorf("%w") 包装过的 StatusError
+func ForError(err error) int {
+	if err == nil {
+		return OK
+	}
+
+	var coder Coder
+	if errors.As(err, &coder) {
+		return coder.ExitCode()
+	}
+
+	switch {
+	case apierrors.IsNotFound(err):
+		return NotFound
+	case apierrors.IsAlreadyExists(err):
+		return AlreadyExists
+	case apierrors.IsForbidden(err):
+		return Forbidden
+	case apierrors.IsConflict(err):
+		return Conflict
+	case apierrors.IsUnauthorized(err):
+		return Unauthorized
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err):
+		return Invalid
+	case isTimeout(err):
+		return Timeout
+	default:
+		return Error
+	}
+}
+
+func isTimeout(err error) bool {
+	return apierrors.IsTimeout(err) ||
+		apierrors.IsServerTimeout(err) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		wait.Interrupted(err)
+}
+
+// Summarize 把错误整理成 Summary
+func Summarize(err error) Summary {
+	summary := Summary{
+		Error:    err.Error(),
+		ExitCode: ForError(err),
+	}
+
+	var status apierrors.APIStatus
+	var reasoner Reasoner
+	if errors.As(err, &status) {
+		summary.Reason = status.Status().Reason
+		summary.Code = status.Status().Code
+		summary.Details = status.Status().Details
+	} else if errors.As(err, &reasoner) {
+		summary.Reason = metav1.StatusReason(reasoner.Reason())
+	} else if summary.ExitCode == Timeout {
+		summary.Reason = metav1.StatusReasonTimeout
+	}
+	return summary
+}
+
+// Report 把错误摘要写到 w 中, format 为 json 时输出单行 JSON, 否则输出可读文本
+// 返回值是进程应该使用的退出码
+func Report(w io.Writer, err error, format string) int {
+	summary := Summarize(err)
+	if format == "json" {
+		data, _ := json.Marshal(summary)
+		fmt.Fprintln(w, string(data))
+		return summary.ExitCode
+	}
+
+	if summary.Reason != "" {
+		fmt.Fprintf(w, "Error: %s (reason=%s, exit=%d)\n", summary.Error, summary.Reason, summary.ExitCode)
+	} else {
+		fmt.Fprintf(w, "Error: %s (exit=%d)\n", summary.Error, summary.ExitCode)
+	}
+	return summary.ExitCode
+}
@@ -0,0 +1,60 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"k8s.io/klog"
+
+	"github.com/xlcbingo1999/example-client-go/metrics"
+	"github.com/xlcbingo1999/example-client-go/probe"
+)
+
+// Serve 在 addr 上监听并在后台用 handler 提供服务, ctx 被取消时关闭, name 只用于日志
+// 监听失败(例如端口被占用)时直接返回错误
+func Serve(ctx context.Context, name, addr string, handler http.Handler) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 5 * time.Second}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			klog.Errorf("%s server on %s stopped: %v", name, addr, err)
+		}
+	}()
+
+	klog.Infof("Serving %s on %s", name, listener.Addr())
+	return nil
+}
+
+// StartServers 在后台启动 /metrics 和 probe server, 地址为空时不启动对应的 server, ctx 被取消时关闭
+// registerProbes 向 probe server 注册检查, 通常是 Controller 的 RegisterProbes
+func StartServers(ctx context.Context, metricsAddr, probeAddr string, registerProbes func(server *probe.Server)) error {
+	if metricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		if err := Serve(ctx, "metrics", metricsAddr, mux); err != nil {
+			return fmt.Errorf("start metrics server: %w", err)
+		}
+	}
+	if probeAddr != "" {
+		server := probe.NewServer()
+		registerProbes(server)
+		if err := Serve(ctx, "probe", probeAddr, server.Handler()); err != nil {
+			return fmt.Errorf("start probe server: %w", err)
+		}
+	}
+	return nil
+}
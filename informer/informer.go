@@ -1,45 +1,29 @@
 package informer
 
 import (
+	"context"
+	"fmt"
 	"log"
-	"path/filepath"
 	"time"
 
 	v1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
-	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 
-	rest "k8s.io/client-go/rest"
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/httpserver"
+	"github.com/xlcbingo1999/example-client-go/probe"
 )
 
-var (
-	Kubeconfig string
-	config     *rest.Config
-	err        error
-)
-
-func RunInformer() {
-	// home是家目录，如果能取得家目录的值，就可以用来做默认值
-	if home := homedir.HomeDir(); home != "" {
-		// 如果输入了kubeconfig参数，该参数的值就是kubeconfig文件的绝对路径，
-		// 如果没有输入kubeconfig参数，就用默认路径~/.kube/config
-		Kubeconfig = filepath.Join(home, ".kube", "config")
-	}
-
-	if config, err = rest.InClusterConfig(); err != nil {
-		if config, err = clientcmd.BuildConfigFromFlags("", Kubeconfig); err != nil {
-			panic(err.Error())
-		}
-	}
+// 提供 /healthz, /readyz 和 /debug 的监听地址, 为空时不启动
+var ProbeAddr string
 
+func RunInformer() error {
 	// 创建 Clientset 对象
-	clientset, err := kubernetes.NewForConfig(config)
+	clientset, err := connection.NewClientSet()
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	// 初始化一个Informer Factory, 每隔30s就会重新List一次
@@ -58,20 +42,58 @@ func RunInformer() {
 	stopper := make(chan struct{})
 	defer close(stopper)
 
+	if ProbeAddr != "" {
+		if err := startProbes(informer, stopper); err != nil {
+			return err
+		}
+	}
+
 	// 启动List and Watch 并等待所有启动的Informer的缓存被同步
 	informerFactory.Start(stopper)
 	informerFactory.WaitForCacheSync(stopper)
 
 	// 创建一个Lister, 主要用于list资源使用
 	deployLister := deployInformer.Lister()
-	deployments, err := deployLister.Deployments("default").List(labels.Everything())
+	deployments, err := deployLister.Deployments(connection.NamespaceOr("default")).List(labels.Everything())
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("list deployments from cache: %w", err)
 	}
 	for idx, deploy := range deployments {
 		log.Printf("%d -> %s\n", idx+1, deploy.Name)
 	}
 	<-stopper
+	return nil
+}
+
+// startProbes 通过 HTTP 暴露 informer 的存活、同步状态和本地存储中对象的数量
+func startProbes(informer cache.SharedIndexInformer, stopper chan struct{}) error {
+	server := probe.NewServer()
+	server.AddHealthz("deployment-informer", func() error {
+		if informer.IsStopped() {
+			return fmt.Errorf("informer stopped")
+		}
+		return nil
+	})
+	server.AddReadyz("deployment-informer", func() error {
+		if !informer.HasSynced() {
+			return fmt.Errorf("informer has not synced")
+		}
+		return nil
+	})
+	server.AddDebug("deployment-cache", func() interface{} {
+		return probe.CacheKeyCounts(informer.GetStore().ListKeys())
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-stopper
+		cancel()
+	}()
+	if err := httpserver.Serve(ctx, "probe", ProbeAddr, server.Handler()); err != nil {
+		cancel()
+		return fmt.Errorf("start probe server: %w", err)
+	}
+	return nil
 }
 
 func onAddfunc(obj interface{}) {
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"time"
+)
+
+// Reconcile 的结果, 作为 result 标签的值
+const (
+	ResultSuccess      = "success"
+	ResultError        = "error"
+	ResultRequeue      = "requeue"
+	ResultRequeueAfter = "requeue_after"
+)
+
+var (
+	reconcileTotal = NewCounterVec("controller_reconcile_total",
+		"Total number of reconciliations per controller and result", "controller", "result")
+	reconcileDuration = NewHistogramVec("controller_reconcile_duration_seconds",
+		"Length of time per reconciliation per controller and result", DefBuckets, "controller", "result")
+	informerSynced = NewGaugeFuncVec("controller_informer_synced",
+		"Whether the informer of the controller has synced its cache, 1 for synced", "controller")
+)
+
+// ObserveReconcile 记录一次 Reconcile 的结果和耗时
+func ObserveReconcile(controller, result string, duration time.Duration) {
+	reconcileTotal.WithLabelValues(controller, result).Inc()
+	reconcileDuration.WithLabelValues(controller, result).Observe(duration.Seconds())
+}
+
+// RegisterInformer 在每次抓取时通过 hasSynced 报告 informer 是否已经同步
+func RegisterInformer(controller string, hasSynced func() bool) {
+	informerSynced.Set(controller, func() float64 {
+		if hasSynced() {
+			return 1
+		}
+		return 0
+	})
+}
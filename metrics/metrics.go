@@ -0,0 +1,328 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 没有引入 prometheus client, 这里实现了输出 Prometheus 文本格式所需的最小功能:
+// 带标签的 counter/gauge/histogram 以及在抓取时才计算的 gauge
+
+// DefBuckets 和 prometheus client 的默认 bucket 一致, 单位是秒
+var DefBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ExponentialBuckets 返回 count 个从 start 开始、每个是前一个 factor 倍的 bucket
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	buckets := make([]float64, count)
+	for i := range buckets {
+		buckets[i] = start
+		start *= factor
+	}
+	return buckets
+}
+
+// collector 把自己的所有样本以文本格式写入 w
+type collector interface {
+	collect(w io.Writer)
+}
+
+// Registry 保存所有需要暴露的指标, 按注册顺序输出
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// DefaultRegistry 是 Handler 默认输出的 Registry
+var DefaultRegistry = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.collectors = append(r.collectors, c)
+}
+
+// Write 以 Prometheus 文本格式输出所有指标
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := append([]collector(nil), r.collectors...)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.collect(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler 返回输出 DefaultRegistry 的 http.Handler
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := DefaultRegistry.Write(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// sample 是一个带有具体标签值的指标
+type sample interface {
+	write(w io.Writer, name, labels string)
+}
+
+// vec 是同名指标的集合, 每组标签值对应一个 sample
+type vec[S sample] struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	newSample  func() S
+
+	mu      sync.Mutex
+	samples map[string]S
+	labels  map[string]string
+}
+
+func newVec[S sample](name, help, kind string, labelNames []string, newSample func() S) *vec[S] {
+	v := &vec[S]{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		newSample:  newSample,
+		samples:    map[string]S{},
+		labels:     map[string]string{},
+	}
+	DefaultRegistry.register(v)
+	return v
+}
+
+// WithLabelValues 返回标签值对应的 sample, 第一次使用时创建
+func (v *vec[S]) WithLabelValues(values ...string) S {
+	if len(values) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expects %d label values, got %d", v.name, len(v.labelNames), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	s, ok := v.samples[key]
+	if !ok {
+		s = v.newSample()
+		v.samples[key] = s
+		v.labels[key] = formatLabels(v.labelNames, values)
+	}
+	return s
+}
+
+func (v *vec[S]) collect(w io.Writer) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	writeHeader(w, v.name, v.help, v.kind)
+	keys := make([]string, 0, len(v.samples))
+	for key := range v.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		v.samples[key].write(w, v.name, v.labels[key])
+	}
+}
+
+// Counter 是只增不减的计数器
+type Counter struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) Add(delta float64) {
+	c.mu.Lock()
+	c.value += delta
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer, name, labels string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeSample(w, name, labels, c.value)
+}
+
+// Gauge 是可以任意设置的数值
+type Gauge struct {
+	mu    sync.Mutex
+	value float64
+}
+
+func (g *Gauge) Set(value float64) {
+	g.mu.Lock()
+	g.value = value
+	g.mu.Unlock()
+}
+
+func (g *Gauge) Inc() { g.Add(1) }
+
+func (g *Gauge) Dec() { g.Add(-1) }
+
+func (g *Gauge) Add(delta float64) {
+	g.mu.Lock()
+	g.value += delta
+	g.mu.Unlock()
+}
+
+func (g *Gauge) write(w io.Writer, name, labels string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	writeSample(w, name, labels, g.value)
+}
+
+// Histogram 统计观测值落在各个 bucket 中的次数
+type Histogram struct {
+	buckets []float64
+
+	mu     sync.Mutex
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]uint64, len(buckets))}
+}
+
+func (h *Histogram) Observe(value float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// 输出时再累加, 这里只记录第一个能容纳 value 的 bucket
+	if i := sort.SearchFloat64s(h.buckets, value); i < len(h.buckets) {
+		h.counts[i]++
+	}
+	h.sum += value
+	h.count++
+}
+
+func (h *Histogram) write(w io.Writer, name, labels string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var cumulative uint64
+	for i, upper := range h.buckets {
+		cumulative += h.counts[i]
+		writeSample(w, name+"_bucket", appendLabel(labels, "le", formatFloat(upper)), float64(cumulative))
+	}
+	writeSample(w, name+"_bucket", appendLabel(labels, "le", "+Inf"), float64(h.count))
+	writeSample(w, name+"_sum", labels, h.sum)
+	writeSample(w, name+"_count", labels, float64(h.count))
+}
+
+// CounterVec, GaugeVec 和 HistogramVec 是带标签的指标
+type (
+	CounterVec   struct{ *vec[*Counter] }
+	GaugeVec     struct{ *vec[*Gauge] }
+	HistogramVec struct{ *vec[*Histogram] }
+)
+
+func NewCounterVec(name, help string, labelNames ...string) CounterVec {
+	return CounterVec{newVec(name, help, "counter", labelNames, func() *Counter { return &Counter{} })}
+}
+
+func NewGaugeVec(name, help string, labelNames ...string) GaugeVec {
+	return GaugeVec{newVec(name, help, "gauge", labelNames, func() *Gauge { return &Gauge{} })}
+}
+
+func NewHistogramVec(name, help string, buckets []float64, labelNames ...string) HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return HistogramVec{newVec(name, help, "histogram", labelNames, func() *Histogram { return newHistogram(sorted) })}
+}
+
+// GaugeFuncVec 的值在每次抓取时调用注册的函数得到
+type GaugeFuncVec struct {
+	name      string
+	help      string
+	labelName string
+
+	mu    sync.Mutex
+	funcs map[string]func() float64
+}
+
+func NewGaugeFuncVec(name, help, labelName string) *GaugeFuncVec {
+	g := &GaugeFuncVec{name: name, help: help, labelName: labelName, funcs: map[string]func() float64{}}
+	DefaultRegistry.register(g)
+	return g
+}
+
+// Set 注册标签值对应的函数, 同一个标签值再次注册时替换之前的函数
+func (g *GaugeFuncVec) Set(labelValue string, f func() float64) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.funcs[labelValue] = f
+}
+
+func (g *GaugeFuncVec) collect(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	writeHeader(w, g.name, g.help, "gauge")
+	values := make([]string, 0, len(g.funcs))
+	for value := range g.funcs {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	for _, value := range values {
+		writeSample(w, g.name, formatLabels([]string{g.labelName}, []string{value}), g.funcs[value]())
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w io.Writer, name, labels string, value float64) {
+	if labels != "" {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, labels, formatFloat(value))
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", name, formatFloat(value))
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels 返回 a="x",b="y" 格式的标签
+func formatLabels(names, values []string) string {
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelValueEscaper.Replace(values[i]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+func appendLabel(labels, name, value string) string {
+	label := formatLabels([]string{name}, []string{value})
+	if labels == "" {
+		return label
+	}
+	return labels + "," + label
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"k8s.io/client-go/util/workqueue"
+)
+
+// workqueue 的指标, 名字和 k8s.io/component-base/metrics/prometheus/workqueue 保持一致, name 标签是队列的名字
+var (
+	workqueueDepth = NewGaugeVec("workqueue_depth",
+		"Current depth of workqueue", "name")
+	workqueueAdds = NewCounterVec("workqueue_adds_total",
+		"Total number of adds handled by workqueue", "name")
+	workqueueLatency = NewHistogramVec("workqueue_queue_duration_seconds",
+		"How long in seconds an item stays in workqueue before being requested.", ExponentialBuckets(10e-9, 10, 10), "name")
+	workqueueWorkDuration = NewHistogramVec("workqueue_work_duration_seconds",
+		"How long in seconds processing an item from workqueue takes.", ExponentialBuckets(10e-9, 10, 10), "name")
+	workqueueUnfinishedWork = NewGaugeVec("workqueue_unfinished_work_seconds",
+		"How many seconds of work has been done that is in progress and hasn't been observed by work_duration.", "name")
+	workqueueLongestRunningProcessor = NewGaugeVec("workqueue_longest_running_processor_seconds",
+		"How many seconds has the longest running processor for workqueue been running.", "name")
+	workqueueRetries = NewCounterVec("workqueue_retries_total",
+		"Total number of retries handled by workqueue", "name")
+)
+
+// workqueue.SetProvider 只有第一次调用生效, 而且只对之后创建的有名字的队列生效,
+// 所以在包初始化时注册, 导入这个包的 controller 创建队列时就会使用它
+func init() {
+	workqueue.SetProvider(workqueueMetricsProvider{})
+}
+
+type workqueueMetricsProvider struct{}
+
+func (workqueueMetricsProvider) NewDepthMetric(name string) workqueue.GaugeMetric {
+	return workqueueDepth.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewAddsMetric(name string) workqueue.CounterMetric {
+	return workqueueAdds.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLatencyMetric(name string) workqueue.HistogramMetric {
+	return workqueueLatency.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewWorkDurationMetric(name string) workqueue.HistogramMetric {
+	return workqueueWorkDuration.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewUnfinishedWorkSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueUnfinishedWork.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewLongestRunningProcessorSecondsMetric(name string) workqueue.SettableGaugeMetric {
+	return workqueueLongestRunningProcessor.WithLabelValues(name)
+}
+
+func (workqueueMetricsProvider) NewRetriesMetric(name string) workqueue.CounterMetric {
+	return workqueueRetries.WithLabelValues(name)
+}
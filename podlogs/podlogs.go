@@ -0,0 +1,272 @@
+package podlogs
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/utils/ptr"
+
+	"github.com/xlcbingo1999/example-client-go/clientset"
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/exitcode"
+)
+
+// logs 命令的参数
+var (
+	// 通过 deployment 的 selector 找到 pod, 默认是 clientset_demo 创建的 deployment
+	Deployment string
+	// 直接指定 pod 的标签选择器, 设置后忽略 Deployment
+	Selector string
+	// 只看指定名字的容器, 为空时看所有容器
+	Container string
+
+	Follow     bool
+	Previous   bool
+	Timestamps bool
+	// 只看最近一段时间的日志, 0 表示不限制
+	Since time.Duration
+	// 每个容器只看最后几行, 小于 0 表示全部
+	Tail int64
+)
+
+func RunPodLogs() error {
+	clientset, err := connection.NewClientSet()
+	if err != nil {
+		return err
+	}
+	namespace := connection.NamespaceOr(defaultNamespace())
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	selector, err := resolveSelector(ctx, clientset, namespace)
+	if err != nil {
+		return err
+	}
+	log.Printf("Streaming logs of pods matching %q in %s\n", selector.String(), namespace)
+
+	streamer := newStreamer(clientset, os.Stdout)
+	if !Follow {
+		return streamer.streamAll(ctx, namespace, selector)
+	}
+	return streamer.follow(ctx, namespace, selector)
+}
+
+// defaultNamespace 没有指定 --selector 时默认看 clientset_demo 的 deployment
+func defaultNamespace() string {
+	if Selector != "" {
+		return apiv1.NamespaceDefault
+	}
+	return clientset.NAMESPACE
+}
+
+// resolveSelector 把 --deployment 或 --selector 转换成 pod 的标签选择器
+func resolveSelector(ctx context.Context, clientset kubernetes.Interface, namespace string) (labels.Selector, error) {
+	if Selector != "" {
+		selector, err := labels.Parse(Selector)
+		if err != nil {
+			return nil, &exitcode.UsageError{Err: fmt.Errorf("invalid label selector: %w", err)}
+		}
+		return selector, nil
+	}
+
+	deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, Deployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("get deployment %s/%s: %w", namespace, Deployment, err)
+	}
+	return metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+}
+
+// streamer 并发读取多个容器的日志, 每一行都加上 [pod/container] 前缀
+type streamer struct {
+	clientset kubernetes.Interface
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	// 正在读取的容器, value 是开始读取时容器的重启次数, follow 模式下用于发现重启后的新容器
+	mu     sync.Mutex
+	active map[string]int32
+	wg     sync.WaitGroup
+}
+
+func newStreamer(clientset kubernetes.Interface, out io.Writer) *streamer {
+	return &streamer{
+		clientset: clientset,
+		out:       out,
+		active:    map[string]int32{},
+	}
+}
+
+// streamAll 读取当前所有匹配 pod 的日志, 读完后返回
+func (s *streamer) streamAll(ctx context.Context, namespace string, selector labels.Selector) error {
+	pods, err := s.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return fmt.Errorf("list pods in %s: %w", namespace, err)
+	}
+	if len(pods.Items) == 0 {
+		log.Printf("No pods found in %s\n", namespace)
+		return nil
+	}
+
+	var errsMu sync.Mutex
+	var errs []error
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		for _, container := range containersOf(pod) {
+			s.wg.Add(1)
+			go func(pod *apiv1.Pod, container string) {
+				defer s.wg.Done()
+				if err := s.stream(ctx, pod, container); err != nil {
+					errsMu.Lock()
+					errs = append(errs, err)
+					errsMu.Unlock()
+				}
+			}(pod, container)
+		}
+	}
+	s.wg.Wait()
+	return utilerrors.NewAggregate(errs)
+}
+
+// follow 持续读取日志, 并通过 informer 发现新创建的 pod 和重启后的容器
+func (s *streamer) follow(ctx context.Context, namespace string, selector labels.Selector) error {
+	factory := informers.NewSharedInformerFactoryWithOptions(s.clientset, 0,
+		informers.WithNamespace(namespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.LabelSelector = selector.String()
+		}))
+	informer := factory.Core().V1().Pods().Informer()
+
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { s.startReady(ctx, obj) },
+		UpdateFunc: func(oldObj, newObj interface{}) { s.startReady(ctx, newObj) },
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	<-ctx.Done()
+	factory.Shutdown()
+	s.wg.Wait()
+	return nil
+}
+
+// startReady 为 pod 中已经启动、且还没有在读取的容器开始读取日志
+func (s *streamer) startReady(ctx context.Context, obj interface{}) {
+	pod, ok := obj.(*apiv1.Pod)
+	if !ok {
+		return
+	}
+
+	for _, status := range append(append([]apiv1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...) {
+		if Container != "" && status.Name != Container {
+			continue
+		}
+		// 还在等待启动的容器没有日志可读
+		if status.State.Running == nil && status.State.Terminated == nil {
+			continue
+		}
+
+		key := pod.Name + "/" + status.Name
+		s.mu.Lock()
+		restarts, streaming := s.active[key]
+		if streaming && restarts >= status.RestartCount {
+			s.mu.Unlock()
+			continue
+		}
+		s.active[key] = status.RestartCount
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func(pod *apiv1.Pod, container string) {
+			defer s.wg.Done()
+			if err := s.stream(ctx, pod, container); err != nil && ctx.Err() == nil {
+				log.Println(err)
+			}
+		}(pod, status.Name)
+	}
+}
+
+// stream 读取单个容器的日志直到结束
+func (s *streamer) stream(ctx context.Context, pod *apiv1.Pod, container string) error {
+	opts := &apiv1.PodLogOptions{
+		Container:  container,
+		Follow:     Follow,
+		Previous:   Previous,
+		Timestamps: Timestamps,
+	}
+	if Since > 0 {
+		opts.SinceSeconds = ptr.To(int64(Since.Seconds()))
+	}
+	if Tail >= 0 {
+		opts.TailLines = ptr.To(Tail)
+	}
+
+	rc, err := s.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return fmt.Errorf("stream logs of %s/%s: %w", pod.Name, container, err)
+	}
+	defer rc.Close()
+
+	prefix := fmt.Sprintf("[%s/%s] ", pod.Name, container)
+	reader := bufio.NewReader(rc)
+	for {
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			s.write(prefix, line)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("read logs of %s/%s: %w", pod.Name, container, err)
+		}
+	}
+}
+
+// write 保证不同容器的日志行不会交错输出
+func (s *streamer) write(prefix, line string) {
+	s.outMu.Lock()
+	defer s.outMu.Unlock()
+
+	fmt.Fprint(s.out, prefix, line)
+	if line[len(line)-1] != '\n' {
+		fmt.Fprintln(s.out)
+	}
+}
+
+// containersOf 返回需要读取日志的容器名
+func containersOf(pod *apiv1.Pod) []string {
+	if Container != "" {
+		return []string{Container}
+	}
+
+	var names []string
+	for _, c := range pod.Spec.InitContainers {
+		names = append(names, c.Name)
+	}
+	for _, c := range pod.Spec.Containers {
+		names = append(names, c.Name)
+	}
+	return names
+}
@@ -0,0 +1,154 @@
+package probe
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Check 返回 nil 表示检查通过
+type Check func() error
+
+// Server 提供 /healthz, /readyz 和 /debug, 用作 pod 的 liveness/readiness 探针和排查问题
+type Server struct {
+	mu      sync.Mutex
+	healthz map[string]Check
+	readyz  map[string]Check
+	debug   map[string]func() interface{}
+	// 额外的调试接口, 例如重新处理失败的 key
+	handlers map[string]http.Handler
+}
+
+func NewServer() *Server {
+	return &Server{
+		healthz: map[string]Check{},
+		readyz:  map[string]Check{},
+		debug:   map[string]func() interface{}{},
+
+		handlers: map[string]http.Handler{},
+	}
+}
+
+// AddHealthz 添加存活检查, 任何一个失败时 /healthz 返回 500
+func (s *Server) AddHealthz(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.healthz[name] = check
+}
+
+// AddReadyz 添加就绪检查, 任何一个失败时 /readyz 返回 500
+func (s *Server) AddReadyz(name string, check Check) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.readyz[name] = check
+}
+
+// AddDebug 添加调试信息, /debug 以 JSON 输出所有 dump 的结果, 以 name 为键
+func (s *Server) AddDebug(name string, dump func() interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.debug[name] = dump
+}
+
+// AddHandler 在 pattern 上注册额外的接口, 需要在 Handler 之前调用
+func (s *Server) AddHandler(pattern string, handler http.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[pattern] = handler
+}
+
+func (s *Server) Handler() http.Handler {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	mux := http.NewServeMux()
+	for pattern, handler := range s.handlers {
+		mux.Handle(pattern, handler)
+	}
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) { s.serveChecks(w, r, s.healthz) })
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) { s.serveChecks(w, r, s.readyz) })
+	mux.HandleFunc("/debug", s.serveDebug)
+	return mux
+}
+
+// serveChecks 和 apiserver 的 /healthz 一样, 全部通过时返回 ok, 带 ?verbose 时列出每一项的结果
+func (s *Server) serveChecks(w http.ResponseWriter, r *http.Request, checks map[string]Check) {
+	s.mu.Lock()
+	copied := make(map[string]Check, len(checks))
+	names := make([]string, 0, len(checks))
+	for name, check := range checks {
+		copied[name] = check
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	sort.Strings(names)
+
+	var report strings.Builder
+	failed := false
+	for _, name := range names {
+		if err := copied[name](); err != nil {
+			failed = true
+			fmt.Fprintf(&report, "[-]%s failed: %v\n", name, err)
+			continue
+		}
+		fmt.Fprintf(&report, "[+]%s ok\n", name)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	if failed {
+		w.WriteHeader(http.StatusInternalServerError)
+		fmt.Fprint(w, report.String())
+		fmt.Fprintf(w, "%s check failed\n", strings.TrimPrefix(r.URL.Path, "/"))
+		return
+	}
+	if _, verbose := r.URL.Query()["verbose"]; verbose {
+		fmt.Fprint(w, report.String())
+	}
+	fmt.Fprint(w, "ok")
+}
+
+func (s *Server) serveDebug(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	dumps := make(map[string]func() interface{}, len(s.debug))
+	for name, dump := range s.debug {
+		dumps[name] = dump
+	}
+	s.mu.Unlock()
+
+	result := make(map[string]interface{}, len(dumps))
+	for name, dump := range dumps {
+		result[name] = dump()
+	}
+	WriteJSON(w, result)
+}
+
+// WriteJSON 以缩进的 JSON 格式输出 v
+func WriteJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+// CacheKeyCounts 返回本地存储中对象的总数和每个 namespace 的数量
+func CacheKeyCounts(keys []string) map[string]interface{} {
+	namespaces := map[string]int{}
+	for _, key := range keys {
+		namespace := ""
+		if i := strings.Index(key, "/"); i >= 0 {
+			namespace = key[:i]
+		}
+		namespaces[namespace]++
+	}
+	return map[string]interface{}{
+		"total":      len(keys),
+		"namespaces": namespaces,
+	}
+}
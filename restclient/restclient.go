@@ -2,40 +2,23 @@ package restclient
 
 import (
 	"context"
-	"flag"
 	"fmt"
-	"path/filepath"
 
+	"github.com/xlcbingo1999/example-client-go/connection"
 	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-	"k8s.io/client-go/util/homedir"
 	"k8s.io/kubectl/pkg/scheme"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func RunRestClient() {
-	var kubeconfig *string
-
-	// home是家目录，如果能取得家目录的值，就可以用来做默认值
-	if home := homedir.HomeDir(); home != "" {
-		// 如果输入了kubeconfig参数，该参数的值就是kubeconfig文件的绝对路径，
-		// 如果没有输入kubeconfig参数，就用默认路径~/.kube/config
-		kubeconfig = flag.String("kubeconfig", filepath.Join(home, ".kube", "config"), "(optional) absolute path to the kubeconfig file")
-	} else {
-		// 如果取不到当前用户的家目录，就没办法设置kubeconfig的默认目录了，只能从入参中取
-		kubeconfig = flag.String("kubeconfig", "", "absolute path to the kubeconfig file")
-	}
-
-	flag.Parse()
-
-	// 从本机加载kubeconfig配置文件，因此第一个参数为空字符串
-	config, err := clientcmd.BuildConfigFromFlags("", *kubeconfig)
+func RunRestClient() error {
+	// 加载集群配置, 路径和context由 --kubeconfig/--context 指定
+	config, err := connection.RESTConfig()
 
 	// kubeconfig加载失败就直接退出了
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	// 参考path : /api/v1/namespaces/{namespace}/pods
@@ -49,14 +32,14 @@ func RunRestClient() {
 	restClient, err := rest.RESTClientFor(config)
 
 	if err != nil {
-		panic(err.Error())
+		return err
 	}
 
 	// 保存pod结果的数据结构实例
 	result := &corev1.PodList{}
 
 	//  指定namespace
-	namespace := "kube-system"
+	namespace := connection.NamespaceOr("kube-system")
 	// 设置请求参数，然后发起请求
 	// GET请求
 	err = restClient.Get().
@@ -72,7 +55,7 @@ func RunRestClient() {
 		Into(result)
 
 	if err != nil {
-		panic(err.Error())
+		return fmt.Errorf("list pods in %s: %w", namespace, err)
 	}
 
 	// 表头
@@ -85,4 +68,5 @@ func RunRestClient() {
 			d.Status.Phase,
 			d.Name)
 	}
+	return nil
 }
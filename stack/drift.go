@@ -0,0 +1,158 @@
+package stack
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// DeploymentDrift 返回 current 相对于 desired 发生变化的字段, current 中 apiserver 填充的默认值不算变化
+// selector 不可修改, 调用方需要自己比较
+func DeploymentDrift(current, desired *appsv1.Deployment) []string {
+	var drift []string
+
+	// Semantic 把 nil 和空列表视为相等, 并按数值比较 100m 和 0.1 这样的资源数量
+	if !equality.Semantic.DeepEqual(current.Labels, desired.Labels) {
+		drift = append(drift, "labels")
+	}
+	if ReplicasOf(current.Spec.Replicas) != ReplicasOf(desired.Spec.Replicas) {
+		drift = append(drift, fmt.Sprintf("replicas %d -> %d", ReplicasOf(current.Spec.Replicas), ReplicasOf(desired.Spec.Replicas)))
+	}
+	if !equality.Semantic.DeepEqual(current.Spec.Template.Labels, desired.Spec.Template.Labels) {
+		drift = append(drift, "template labels")
+	}
+
+	currentContainers := map[string]apiv1.Container{}
+	for _, c := range current.Spec.Template.Spec.Containers {
+		currentContainers[c.Name] = c
+	}
+	if len(currentContainers) != len(desired.Spec.Template.Spec.Containers) {
+		drift = append(drift, "containers")
+	}
+	for _, want := range desired.Spec.Template.Spec.Containers {
+		got, ok := currentContainers[want.Name]
+		if !ok {
+			drift = append(drift, fmt.Sprintf("container %s missing", want.Name))
+			continue
+		}
+		if got.Image != want.Image {
+			drift = append(drift, fmt.Sprintf("container %s image %s -> %s", want.Name, got.Image, want.Image))
+		}
+		if want.ImagePullPolicy != "" && got.ImagePullPolicy != want.ImagePullPolicy {
+			drift = append(drift, fmt.Sprintf("container %s imagePullPolicy %s -> %s", want.Name, got.ImagePullPolicy, want.ImagePullPolicy))
+		}
+		if !equality.Semantic.DeepEqual(normalizeContainerPorts(got.Ports), normalizeContainerPorts(want.Ports)) {
+			drift = append(drift, fmt.Sprintf("container %s ports", want.Name))
+		}
+		if !equality.Semantic.DeepEqual(normalizeEnv(got.Env), normalizeEnv(want.Env)) {
+			drift = append(drift, fmt.Sprintf("container %s env", want.Name))
+		}
+		if !equality.Semantic.DeepEqual(normalizeResources(got.Resources), normalizeResources(want.Resources)) {
+			drift = append(drift, fmt.Sprintf("container %s resources", want.Name))
+		}
+	}
+	return drift
+}
+
+// ServiceDrift 返回 current 相对于 desired 发生变化的字段, clusterIP 和已经分配的 nodePort 不算变化
+func ServiceDrift(current, desired *apiv1.Service) []string {
+	var drift []string
+
+	if !equality.Semantic.DeepEqual(current.Labels, desired.Labels) {
+		drift = append(drift, "labels")
+	}
+	if current.Spec.Type != desired.Spec.Type {
+		drift = append(drift, fmt.Sprintf("type %s -> %s", current.Spec.Type, desired.Spec.Type))
+	}
+	if !equality.Semantic.DeepEqual(current.Spec.Selector, desired.Spec.Selector) {
+		drift = append(drift, "selector")
+	}
+	if !equality.Semantic.DeepEqual(normalizeServicePorts(current.Spec.Ports, nil), normalizeServicePorts(desired.Spec.Ports, current.Spec.Ports)) {
+		drift = append(drift, "ports")
+	}
+	return drift
+}
+
+// normalizeContainerPorts 补齐 apiserver 会设置的默认协议
+func normalizeContainerPorts(ports []apiv1.ContainerPort) []apiv1.ContainerPort {
+	normalized := make([]apiv1.ContainerPort, 0, len(ports))
+	for _, p := range ports {
+		p.Protocol = ProtocolOf(p.Protocol)
+		normalized = append(normalized, p)
+	}
+	return normalized
+}
+
+// normalizeEnv 补齐 apiserver 给 fieldRef 设置的默认 apiVersion
+func normalizeEnv(env []apiv1.EnvVar) []apiv1.EnvVar {
+	normalized := make([]apiv1.EnvVar, 0, len(env))
+	for _, e := range env {
+		if e.ValueFrom != nil && e.ValueFrom.FieldRef != nil && e.ValueFrom.FieldRef.APIVersion == "" {
+			e = *e.DeepCopy()
+			e.ValueFrom.FieldRef.APIVersion = "v1"
+		}
+		normalized = append(normalized, e)
+	}
+	return normalized
+}
+
+// normalizeResources 和 apiserver 一样, 只设置了 limits 的资源把 requests 默认为 limits
+func normalizeResources(resources apiv1.ResourceRequirements) apiv1.ResourceRequirements {
+	normalized := *resources.DeepCopy()
+	for name, limit := range normalized.Limits {
+		if _, ok := normalized.Requests[name]; ok {
+			continue
+		}
+		if normalized.Requests == nil {
+			normalized.Requests = apiv1.ResourceList{}
+		}
+		normalized.Requests[name] = limit.DeepCopy()
+	}
+	return normalized
+}
+
+// normalizeServicePorts 补齐 apiserver 会设置的默认协议和 targetPort
+// 没有指定 nodePort 的端口沿用 allocated 中由 apiserver 分配的同名端口的 nodePort
+func normalizeServicePorts(ports, allocated []apiv1.ServicePort) []apiv1.ServicePort {
+	normalized := make([]apiv1.ServicePort, 0, len(ports))
+	for _, p := range ports {
+		if p.NodePort == 0 {
+			for _, a := range allocated {
+				if a.Name == p.Name {
+					p.NodePort = a.NodePort
+				}
+			}
+		}
+		p.Protocol = ProtocolOf(p.Protocol)
+		p.TargetPort = TargetPortOf(p)
+		normalized = append(normalized, p)
+	}
+	return normalized
+}
+
+// TargetPortOf 返回 service 端口的 targetPort, 没有设置时 apiserver 会使用 port
+func TargetPortOf(port apiv1.ServicePort) intstr.IntOrString {
+	if port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal == 0 {
+		return intstr.FromInt32(port.Port)
+	}
+	return port.TargetPort
+}
+
+// ProtocolOf 返回端口的协议, 没有设置时 apiserver 默认为 TCP
+func ProtocolOf(protocol apiv1.Protocol) apiv1.Protocol {
+	if protocol == "" {
+		return apiv1.ProtocolTCP
+	}
+	return protocol
+}
+
+// ReplicasOf 返回 deployment 的副本数, 没有设置时 apiserver 默认为 1
+func ReplicasOf(p *int32) int32 {
+	if p == nil {
+		return 1
+	}
+	return *p
+}
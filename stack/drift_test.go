@@ -0,0 +1,67 @@
+package stack
+
+import (
+	"testing"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testApp(env ...apiv1.EnvVar) *App {
+	return &App{
+		Namespace:      "default",
+		DeploymentName: "web",
+		ServiceName:    "web",
+		Labels:         map[string]string{"app": "web"},
+		ContainerName:  "web",
+		Image:          "nginx:1.25",
+		Replicas:       1,
+		ContainerPort:  8080,
+		Env:            env,
+		ServiceType:    apiv1.ServiceTypeNodePort,
+		ServicePort:    8080,
+	}
+}
+
+func TestDeploymentDriftIgnoresDefaults(t *testing.T) {
+	app := testApp(apiv1.EnvVar{Name: "POD_NAME", ValueFrom: &apiv1.EnvVarSource{FieldRef: &apiv1.ObjectFieldSelector{FieldPath: "metadata.name"}}})
+	app.Resources.Limits = apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("256Mi")}
+	// apiserver 填充的默认值
+	existing := NewDeployment(app)
+	existing.Spec.Template.Spec.RestartPolicy = apiv1.RestartPolicyAlways
+	container := &existing.Spec.Template.Spec.Containers[0]
+	container.TerminationMessagePath = apiv1.TerminationMessagePathDefault
+	container.Env[0].ValueFrom.FieldRef.APIVersion = "v1"
+	container.Resources.Requests = apiv1.ResourceList{apiv1.ResourceMemory: resource.MustParse("256Mi")}
+
+	if drift := DeploymentDrift(existing, NewDeployment(app)); len(drift) != 0 {
+		t.Errorf("DeploymentDrift() = %v for an unchanged app with defaulted fields", drift)
+	}
+}
+
+func TestDeploymentDriftDetectsRemovedEnv(t *testing.T) {
+	existing := NewDeployment(testApp(apiv1.EnvVar{Name: "FOO", Value: "bar"}))
+
+	if drift := DeploymentDrift(existing, NewDeployment(testApp())); len(drift) == 0 {
+		t.Error("DeploymentDrift() is empty after all env vars were removed")
+	}
+	if drift := DeploymentDrift(existing, NewDeployment(testApp(apiv1.EnvVar{Name: "FOO", Value: "baz"}))); len(drift) == 0 {
+		t.Error("DeploymentDrift() is empty after an env value changed")
+	}
+}
+
+func TestServiceDriftKeepsAllocatedNodePort(t *testing.T) {
+	app := testApp()
+	existing := NewService(app)
+	existing.Spec.ClusterIP = "10.0.0.10"
+	existing.Spec.Ports[0].NodePort = 30080
+
+	if drift := ServiceDrift(existing, NewService(app)); len(drift) != 0 {
+		t.Errorf("ServiceDrift() = %v for an unchanged app with an allocated nodePort", drift)
+	}
+
+	app.ServicePort = 80
+	if drift := ServiceDrift(existing, NewService(app)); len(drift) != 1 || drift[0] != "ports" {
+		t.Errorf("ServiceDrift() = %v, want [ports]", drift)
+	}
+}
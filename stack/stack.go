@@ -0,0 +1,100 @@
+// Package stack 把一个单容器的 web 服务展开成 Deployment 和 Service, 并比较集群中的对象是否发生漂移
+// clientset_demo 和 webapp_demo 共用这里的结构和比较规则
+package stack
+
+import (
+	"maps"
+
+	appsv1 "k8s.io/api/apps/v1"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/ptr"
+)
+
+// PortName 是容器端口和 service 端口的名字, service 通过它转发到容器
+const PortName = "http"
+
+// App 描述一个单容器的 web 服务
+type App struct {
+	Namespace      string
+	DeploymentName string
+	ServiceName    string
+	// 同时作为对象标签, deployment selector, pod 模板标签和 service selector
+	Labels          map[string]string
+	OwnerReferences []metav1.OwnerReference
+
+	ContainerName string
+	Image         string
+	Replicas      int32
+	ContainerPort int32
+	Env           []apiv1.EnvVar
+	Resources     apiv1.ResourceRequirements
+
+	ServiceType apiv1.ServiceType
+	ServicePort int32
+	// 为 0 时由 apiserver 自动分配, ClusterIP 类型的 service 会忽略该字段
+	NodePort int32
+}
+
+func (a *App) objectMeta(name string) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       a.Namespace,
+		Labels:          maps.Clone(a.Labels),
+		OwnerReferences: a.OwnerReferences,
+	}
+}
+
+func NewDeployment(a *App) *appsv1.Deployment {
+	return &appsv1.Deployment{
+		ObjectMeta: a.objectMeta(a.DeploymentName),
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To(a.Replicas),
+			Selector: &metav1.LabelSelector{
+				MatchLabels: maps.Clone(a.Labels),
+			},
+			Template: apiv1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: maps.Clone(a.Labels),
+				},
+				Spec: apiv1.PodSpec{
+					Containers: []apiv1.Container{{
+						Name:            a.ContainerName,
+						Image:           a.Image,
+						ImagePullPolicy: apiv1.PullIfNotPresent,
+						Env:             a.Env,
+						Resources:       a.Resources,
+						Ports: []apiv1.ContainerPort{{
+							Name:          PortName,
+							Protocol:      apiv1.ProtocolTCP,
+							ContainerPort: a.ContainerPort,
+						}},
+					}},
+				},
+			},
+		},
+	}
+}
+
+func NewService(a *App) *apiv1.Service {
+	nodePort := a.NodePort
+	if a.ServiceType == apiv1.ServiceTypeClusterIP {
+		nodePort = 0
+	}
+
+	return &apiv1.Service{
+		ObjectMeta: a.objectMeta(a.ServiceName),
+		Spec: apiv1.ServiceSpec{
+			Ports: []apiv1.ServicePort{{
+				Name:       PortName,
+				Protocol:   apiv1.ProtocolTCP,
+				Port:       a.ServicePort,
+				TargetPort: intstr.FromString(PortName),
+				NodePort:   nodePort,
+			}},
+			Selector: maps.Clone(a.Labels),
+			Type:     a.ServiceType,
+		},
+	}
+}
@@ -0,0 +1,103 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// 这里的方法和 deepcopy-gen 生成的代码一致, 修改类型的字段时需要同步修改
+
+func (in *WebApp) DeepCopyInto(out *WebApp) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+func (in *WebApp) DeepCopy() *WebApp {
+	if in == nil {
+		return nil
+	}
+	out := new(WebApp)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *WebApp) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+func (in *WebAppSpec) DeepCopyInto(out *WebAppSpec) {
+	*out = *in
+	if in.Replicas != nil {
+		out.Replicas = new(int32)
+		*out.Replicas = *in.Replicas
+	}
+	if in.Env != nil {
+		out.Env = make([]corev1.EnvVar, len(in.Env))
+		for i := range in.Env {
+			in.Env[i].DeepCopyInto(&out.Env[i])
+		}
+	}
+}
+
+func (in *WebAppSpec) DeepCopy() *WebAppSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WebAppSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *WebAppStatus) DeepCopyInto(out *WebAppStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+}
+
+func (in *WebAppStatus) DeepCopy() *WebAppStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WebAppStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *WebAppList) DeepCopyInto(out *WebAppList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		out.Items = make([]WebApp, len(in.Items))
+		for i := range in.Items {
+			in.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+func (in *WebAppList) DeepCopy() *WebAppList {
+	if in == nil {
+		return nil
+	}
+	out := new(WebAppList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+func (in *WebAppList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WebApp 描述一个无状态的 web 服务, webapp_demo 会把它展开成同名的 Deployment 和 Service
+type WebApp struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WebAppSpec   `json:"spec"`
+	Status WebAppStatus `json:"status,omitempty"`
+}
+
+type WebAppSpec struct {
+	Image string `json:"image"`
+	// 为空时是 1
+	Replicas *int32 `json:"replicas,omitempty"`
+	// 容器监听的端口, 为 0 时是 8080
+	ContainerPort int32           `json:"containerPort,omitempty"`
+	Env           []corev1.EnvVar `json:"env,omitempty"`
+
+	// 为空时是 ClusterIP
+	ServiceType corev1.ServiceType `json:"serviceType,omitempty"`
+	// 为 0 时和 containerPort 相同
+	ServicePort int32 `json:"servicePort,omitempty"`
+}
+
+type WebAppStatus struct {
+	// status 对应的 metadata.generation
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	Replicas           int32 `json:"replicas,omitempty"`
+	ReadyReplicas      int32 `json:"readyReplicas,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// WebApp 的 condition 类型
+const (
+	// Deployment 和 Service 已经和 spec 一致
+	ConditionReconciled = "Reconciled"
+	// 所有副本都已经就绪
+	ConditionAvailable = "Available"
+)
+
+type WebAppList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []WebApp `json:"items"`
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/rest"
+
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+)
+
+// 和 client-gen 生成的 clientset 结构相同, 只保留了 WebApp 一种资源
+
+// Scheme 只包含 WebApp, 用于 RESTClient 的编解码
+var (
+	Scheme         = runtime.NewScheme()
+	Codecs         = serializer.NewCodecFactory(Scheme)
+	parameterCodec = runtime.NewParameterCodec(Scheme)
+)
+
+func init() {
+	// ListOptions 等参数属于 meta/v1
+	metav1.AddToGroupVersion(Scheme, schema.GroupVersion{Version: "v1"})
+	utilruntime.Must(v1alpha1.AddToScheme(Scheme))
+}
+
+type Interface interface {
+	DemoV1alpha1() DemoV1alpha1Interface
+}
+
+type DemoV1alpha1Interface interface {
+	RESTClient() rest.Interface
+	WebApps(namespace string) WebAppInterface
+}
+
+// WebAppInterface 的方法和 typed client 一致, 可以直接作为 controller.ObjectClient 和 controller.StatusClient 使用
+type WebAppInterface interface {
+	Create(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.CreateOptions) (*v1alpha1.WebApp, error)
+	Update(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.UpdateOptions) (*v1alpha1.WebApp, error)
+	UpdateStatus(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.UpdateOptions) (*v1alpha1.WebApp, error)
+	Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error
+	Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.WebApp, error)
+	List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.WebAppList, error)
+	Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error)
+	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.WebApp, error)
+}
+
+type Clientset struct {
+	demoV1alpha1 *DemoV1alpha1Client
+}
+
+func (c *Clientset) DemoV1alpha1() DemoV1alpha1Interface {
+	return c.demoV1alpha1
+}
+
+// NewForConfig 根据 config 创建 clientset, config 不会被修改
+func NewForConfig(c *rest.Config) (*Clientset, error) {
+	config := *c
+	config.APIPath = "/apis"
+	config.GroupVersion = &v1alpha1.SchemeGroupVersion
+	config.NegotiatedSerializer = Codecs.WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = rest.DefaultKubernetesUserAgent()
+	}
+
+	restClient, err := rest.RESTClientFor(&config)
+	if err != nil {
+		return nil, err
+	}
+	return &Clientset{demoV1alpha1: &DemoV1alpha1Client{restClient: restClient}}, nil
+}
+
+type DemoV1alpha1Client struct {
+	restClient rest.Interface
+}
+
+func (c *DemoV1alpha1Client) RESTClient() rest.Interface {
+	return c.restClient
+}
+
+func (c *DemoV1alpha1Client) WebApps(namespace string) WebAppInterface {
+	return &webApps{client: c.restClient, ns: namespace}
+}
+
+type webApps struct {
+	client rest.Interface
+	ns     string
+}
+
+func (c *webApps) Get(ctx context.Context, name string, opts metav1.GetOptions) (*v1alpha1.WebApp, error) {
+	result := &v1alpha1.WebApp{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("webapps").
+		Name(name).
+		VersionedParams(&opts, parameterCodec).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *webApps) List(ctx context.Context, opts metav1.ListOptions) (*v1alpha1.WebAppList, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	result := &v1alpha1.WebAppList{}
+	err := c.client.Get().
+		Namespace(c.ns).
+		Resource("webapps").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *webApps) Watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	var timeout time.Duration
+	if opts.TimeoutSeconds != nil {
+		timeout = time.Duration(*opts.TimeoutSeconds) * time.Second
+	}
+	opts.Watch = true
+	return c.client.Get().
+		Namespace(c.ns).
+		Resource("webapps").
+		VersionedParams(&opts, parameterCodec).
+		Timeout(timeout).
+		Watch(ctx)
+}
+
+func (c *webApps) Create(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.CreateOptions) (*v1alpha1.WebApp, error) {
+	result := &v1alpha1.WebApp{}
+	err := c.client.Post().
+		Namespace(c.ns).
+		Resource("webapps").
+		VersionedParams(&opts, parameterCodec).
+		Body(webApp).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *webApps) Update(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.UpdateOptions) (*v1alpha1.WebApp, error) {
+	result := &v1alpha1.WebApp{}
+	err := c.client.Put().
+		Namespace(c.ns).
+		Resource("webapps").
+		Name(webApp.Name).
+		VersionedParams(&opts, parameterCodec).
+		Body(webApp).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+// UpdateStatus 通过 status 子资源更新, apiserver 会忽略 status 以外的修改
+func (c *webApps) UpdateStatus(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.UpdateOptions) (*v1alpha1.WebApp, error) {
+	result := &v1alpha1.WebApp{}
+	err := c.client.Put().
+		Namespace(c.ns).
+		Resource("webapps").
+		Name(webApp.Name).
+		SubResource("status").
+		VersionedParams(&opts, parameterCodec).
+		Body(webApp).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
+
+func (c *webApps) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return c.client.Delete().
+		Namespace(c.ns).
+		Resource("webapps").
+		Name(name).
+		Body(&opts).
+		Do(ctx).
+		Error()
+}
+
+func (c *webApps) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.WebApp, error) {
+	result := &v1alpha1.WebApp{}
+	err := c.client.Patch(pt).
+		Namespace(c.ns).
+		Resource("webapps").
+		Name(name).
+		SubResource(subresources...).
+		VersionedParams(&opts, parameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return result, err
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+)
+
+// WebAppInformer 同时提供 informer 和基于它的本地存储的 lister
+type WebAppInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() WebAppLister
+}
+
+// NewWebAppInformer 创建监听 namespace 中 WebApp 的 informer, namespace 为空时监听所有 namespace
+func NewWebAppInformer(client Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) WebAppInformer {
+	informer := cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return client.DemoV1alpha1().WebApps(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return client.DemoV1alpha1().WebApps(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&v1alpha1.WebApp{},
+		resyncPeriod,
+		indexers,
+	)
+	return &webAppInformer{informer: informer}
+}
+
+type webAppInformer struct {
+	informer cache.SharedIndexInformer
+}
+
+func (i *webAppInformer) Informer() cache.SharedIndexInformer {
+	return i.informer
+}
+
+func (i *webAppInformer) Lister() WebAppLister {
+	return NewWebAppLister(i.informer.GetIndexer())
+}
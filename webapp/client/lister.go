@@ -0,0 +1,62 @@
+package client
+
+import (
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+)
+
+// WebAppLister 从 informer 的本地存储中读取 WebApp, 返回的对象不能修改
+type WebAppLister interface {
+	List(selector labels.Selector) ([]*v1alpha1.WebApp, error)
+	WebApps(namespace string) WebAppNamespaceLister
+}
+
+type WebAppNamespaceLister interface {
+	List(selector labels.Selector) ([]*v1alpha1.WebApp, error)
+	Get(name string) (*v1alpha1.WebApp, error)
+}
+
+func NewWebAppLister(indexer cache.Indexer) WebAppLister {
+	return &webAppLister{indexer: indexer}
+}
+
+type webAppLister struct {
+	indexer cache.Indexer
+}
+
+func (l *webAppLister) List(selector labels.Selector) (ret []*v1alpha1.WebApp, err error) {
+	err = cache.ListAll(l.indexer, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.WebApp))
+	})
+	return ret, err
+}
+
+func (l *webAppLister) WebApps(namespace string) WebAppNamespaceLister {
+	return &webAppNamespaceLister{indexer: l.indexer, namespace: namespace}
+}
+
+type webAppNamespaceLister struct {
+	indexer   cache.Indexer
+	namespace string
+}
+
+func (l *webAppNamespaceLister) List(selector labels.Selector) (ret []*v1alpha1.WebApp, err error) {
+	err = cache.ListAllByNamespace(l.indexer, l.namespace, selector, func(m interface{}) {
+		ret = append(ret, m.(*v1alpha1.WebApp))
+	})
+	return ret, err
+}
+
+func (l *webAppNamespaceLister) Get(name string) (*v1alpha1.WebApp, error) {
+	obj, exists, err := l.indexer.GetByKey(l.namespace + "/" + name)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NewNotFound(v1alpha1.Resource("webapp"), name)
+	}
+	return obj.(*v1alpha1.WebApp), nil
+}
@@ -0,0 +1,269 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/xlcbingo1999/example-client-go/controller"
+	"github.com/xlcbingo1999/example-client-go/stack"
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+	"github.com/xlcbingo1999/example-client-go/webapp/client"
+)
+
+// condition 和事件的 reason
+const (
+	reasonSynced            = "Synced"
+	reasonSyncFailed        = "SyncFailed"
+	reasonResourceExists    = "ResourceExists"
+	reasonReplicasReady     = "ReplicasReady"
+	reasonReplicasNotReady  = "ReplicasNotReady"
+	reasonRolloutInProgress = "RolloutInProgress"
+	reasonCreated           = "Created"
+	reasonUpdated           = "Updated"
+)
+
+// errResourceExists 表示同名的 Deployment 或 Service 已经存在但不属于这个 WebApp, 不会去覆盖它
+type errResourceExists struct {
+	kind, namespace, name string
+}
+
+func (e *errResourceExists) Error() string {
+	return fmt.Sprintf("%s %s/%s already exists and is not controlled by the WebApp", e.kind, e.namespace, e.name)
+}
+
+// reconciler 保证每个 WebApp 都有一个同名的 Deployment 和 Service, 并把 Deployment 的就绪情况写回 status
+// Deployment 和 Service 的 ownerReference 指向 WebApp, WebApp 删除后由垃圾回收删除它们
+type reconciler struct {
+	kubeClient   kubernetes.Interface
+	webAppClient client.Interface
+	deployments  appslisters.DeploymentLister
+	services     corelisters.ServiceLister
+	store        controller.Store[*v1alpha1.WebApp]
+	recorder     record.EventRecorder
+}
+
+func (r *reconciler) InjectStore(store controller.Store[*v1alpha1.WebApp]) {
+	r.store = store
+}
+
+func (r *reconciler) InjectRecorder(recorder record.EventRecorder) {
+	r.recorder = recorder
+}
+
+func (r *reconciler) Reconcile(ctx context.Context, key string) (controller.Result, error) {
+	webApp, exists, err := r.store.Get(key)
+	if err != nil {
+		return controller.Result{}, fmt.Errorf("fetching webapp %s from store failed: %w", key, err)
+	}
+	if !exists {
+		log.Printf("WebApp %s does not exist anymore\n", key)
+		return controller.Result{}, nil
+	}
+	if webApp.DeletionTimestamp != nil {
+		return controller.Result{}, nil
+	}
+
+	deployment, syncErr := r.syncDeployment(ctx, webApp)
+	if syncErr == nil {
+		syncErr = r.syncService(ctx, webApp)
+	}
+	if syncErr != nil {
+		reason := reasonSyncFailed
+		if _, ok := syncErr.(*errResourceExists); ok {
+			reason = reasonResourceExists
+		}
+		r.recorder.Event(webApp, v1.EventTypeWarning, reason, syncErr.Error())
+	}
+
+	// 同步失败时也要把原因写到 status 中
+	if err := r.updateStatus(ctx, webApp, deployment, syncErr); err != nil {
+		return controller.Result{}, err
+	}
+	return controller.Result{}, syncErr
+}
+
+// syncDeployment 创建或更新 WebApp 对应的 Deployment, 返回集群中当前的 Deployment
+func (r *reconciler) syncDeployment(ctx context.Context, webApp *v1alpha1.WebApp) (*appsv1.Deployment, error) {
+	desired := stack.NewDeployment(newApp(webApp))
+	client := r.kubeClient.AppsV1().Deployments(webApp.Namespace)
+
+	existing, err := r.deployments.Deployments(webApp.Namespace).Get(desired.Name)
+	if apierrors.IsNotFound(err) {
+		created, err := client.Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("create deployment %s/%s: %w", desired.Namespace, desired.Name, err)
+		}
+		r.recorder.Eventf(webApp, v1.EventTypeNormal, reasonCreated, "Created deployment %s", created.Name)
+		return created, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !metav1.IsControlledBy(existing, webApp) {
+		return nil, &errResourceExists{kind: "Deployment", namespace: existing.Namespace, name: existing.Name}
+	}
+
+	drift := stack.DeploymentDrift(existing, desired)
+	if len(drift) == 0 {
+		return existing, nil
+	}
+	updated := existing.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.Spec.Replicas = desired.Spec.Replicas
+	updated.Spec.Template = desired.Spec.Template
+	result, err := client.Update(ctx, updated, metav1.UpdateOptions{})
+	if err != nil {
+		return existing, fmt.Errorf("update deployment %s/%s: %w", existing.Namespace, existing.Name, err)
+	}
+	r.recorder.Eventf(webApp, v1.EventTypeNormal, reasonUpdated, "Updated deployment %s: %s", result.Name, strings.Join(drift, ", "))
+	return result, nil
+}
+
+// syncService 创建或更新 WebApp 对应的 Service
+func (r *reconciler) syncService(ctx context.Context, webApp *v1alpha1.WebApp) error {
+	desired := stack.NewService(newApp(webApp))
+	client := r.kubeClient.CoreV1().Services(webApp.Namespace)
+
+	existing, err := r.services.Services(webApp.Namespace).Get(desired.Name)
+	if apierrors.IsNotFound(err) {
+		created, err := client.Create(ctx, desired, metav1.CreateOptions{})
+		if err != nil {
+			return fmt.Errorf("create service %s/%s: %w", desired.Namespace, desired.Name, err)
+		}
+		r.recorder.Eventf(webApp, v1.EventTypeNormal, reasonCreated, "Created service %s", created.Name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !metav1.IsControlledBy(existing, webApp) {
+		return &errResourceExists{kind: "Service", namespace: existing.Namespace, name: existing.Name}
+	}
+
+	drift := stack.ServiceDrift(existing, desired)
+	if len(drift) == 0 {
+		return nil
+	}
+	// 保留 clusterIP 等由 apiserver 分配的字段, nodePort 为 0 时 apiserver 会沿用已经分配的端口
+	updated := existing.DeepCopy()
+	updated.Labels = desired.Labels
+	updated.Spec.Type = desired.Spec.Type
+	updated.Spec.Ports = desired.Spec.Ports
+	updated.Spec.Selector = desired.Spec.Selector
+	if _, err := client.Update(ctx, updated, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("update service %s/%s: %w", existing.Namespace, existing.Name, err)
+	}
+	r.recorder.Eventf(webApp, v1.EventTypeNormal, reasonUpdated, "Updated service %s: %s", existing.Name, strings.Join(drift, ", "))
+	return nil
+}
+
+// updateStatus 根据同步结果和 Deployment 的状态更新 WebApp 的 status, 没有变化时不会写入
+func (r *reconciler) updateStatus(ctx context.Context, webApp *v1alpha1.WebApp, deployment *appsv1.Deployment, syncErr error) error {
+	updated := webApp.DeepCopy()
+	updated.Status.ObservedGeneration = webApp.Generation
+
+	reconciled := metav1.Condition{
+		Type:    v1alpha1.ConditionReconciled,
+		Status:  metav1.ConditionTrue,
+		Reason:  reasonSynced,
+		Message: "Deployment and Service match the spec",
+	}
+	if syncErr != nil {
+		reconciled.Status = metav1.ConditionFalse
+		reconciled.Reason = reasonSyncFailed
+		if _, ok := syncErr.(*errResourceExists); ok {
+			reconciled.Reason = reasonResourceExists
+		}
+		reconciled.Message = syncErr.Error()
+	}
+
+	available := metav1.Condition{
+		Type:    v1alpha1.ConditionAvailable,
+		Status:  metav1.ConditionFalse,
+		Reason:  reasonReplicasNotReady,
+		Message: "Deployment does not exist",
+	}
+	if deployment != nil {
+		desired := replicas(webApp)
+		updated.Status.Replicas = deployment.Status.Replicas
+		updated.Status.ReadyReplicas = deployment.Status.ReadyReplicas
+		available.Message = fmt.Sprintf("%d/%d replicas ready", deployment.Status.ReadyReplicas, desired)
+		// 和 clientset_demo 等待 rollout 的条件一致, 滚动更新时旧 ReplicaSet 就绪的 pod 不算
+		switch {
+		case deployment.Status.ObservedGeneration < deployment.Generation || deployment.Status.UpdatedReplicas != desired:
+			available.Reason = reasonRolloutInProgress
+			available.Message = fmt.Sprintf("%d/%d replicas updated", deployment.Status.UpdatedReplicas, desired)
+		case deployment.Status.ReadyReplicas >= desired:
+			available.Status = metav1.ConditionTrue
+			available.Reason = reasonReplicasReady
+		}
+	}
+	controller.SetConditions(&updated.Status.Conditions, webApp.Generation, reconciled, available)
+
+	_, err := controller.UpdateStatusIfChanged[*v1alpha1.WebApp](ctx, r.webAppClient.DemoV1alpha1().WebApps(webApp.Namespace), webApp, updated)
+	if err != nil {
+		return fmt.Errorf("webapp %s/%s: %w", webApp.Namespace, webApp.Name, err)
+	}
+	return nil
+}
+
+func replicas(webApp *v1alpha1.WebApp) int32 {
+	if webApp.Spec.Replicas == nil {
+		return 1
+	}
+	return *webApp.Spec.Replicas
+}
+
+func containerPort(webApp *v1alpha1.WebApp) int32 {
+	if webApp.Spec.ContainerPort == 0 {
+		return 8080
+	}
+	return webApp.Spec.ContainerPort
+}
+
+// selectorLabels 同时作为对象标签, deployment selector, pod 模板标签和 service selector
+func selectorLabels(webApp *v1alpha1.WebApp) map[string]string {
+	return map[string]string{"app": webApp.Name}
+}
+
+func ownerReferences(webApp *v1alpha1.WebApp) []metav1.OwnerReference {
+	return []metav1.OwnerReference{*metav1.NewControllerRef(webApp, v1alpha1.SchemeGroupVersion.WithKind("WebApp"))}
+}
+
+// newApp 返回 WebApp 展开成的 Deployment 和 Service, 它们和 WebApp 同名
+func newApp(webApp *v1alpha1.WebApp) *stack.App {
+	serviceType := webApp.Spec.ServiceType
+	if serviceType == "" {
+		serviceType = v1.ServiceTypeClusterIP
+	}
+	port := webApp.Spec.ServicePort
+	if port == 0 {
+		port = containerPort(webApp)
+	}
+
+	return &stack.App{
+		Namespace:       webApp.Namespace,
+		DeploymentName:  webApp.Name,
+		ServiceName:     webApp.Name,
+		Labels:          selectorLabels(webApp),
+		OwnerReferences: ownerReferences(webApp),
+		ContainerName:   "web",
+		Image:           webApp.Spec.Image,
+		Replicas:        replicas(webApp),
+		ContainerPort:   containerPort(webApp),
+		Env:             webApp.Spec.Env,
+		ServiceType:     serviceType,
+		ServicePort:     port,
+	}
+}
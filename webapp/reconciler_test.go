@@ -0,0 +1,254 @@
+package webapp
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	appslisters "k8s.io/client-go/listers/apps/v1"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/xlcbingo1999/example-client-go/controller"
+	"github.com/xlcbingo1999/example-client-go/stack"
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+	"github.com/xlcbingo1999/example-client-go/webapp/client"
+)
+
+// fakeWebAppClient 只实现 reconciler 用到的 UpdateStatus, 并记录每次写入的对象
+type fakeWebAppClient struct {
+	client.WebAppInterface
+	statusUpdates []*v1alpha1.WebApp
+}
+
+func (c *fakeWebAppClient) DemoV1alpha1() client.DemoV1alpha1Interface { return c }
+
+func (c *fakeWebAppClient) RESTClient() rest.Interface { return nil }
+
+func (c *fakeWebAppClient) WebApps(namespace string) client.WebAppInterface { return c }
+
+func (c *fakeWebAppClient) UpdateStatus(ctx context.Context, webApp *v1alpha1.WebApp, opts metav1.UpdateOptions) (*v1alpha1.WebApp, error) {
+	c.statusUpdates = append(c.statusUpdates, webApp.DeepCopy())
+	return webApp, nil
+}
+
+func testWebApp() *v1alpha1.WebApp {
+	return &v1alpha1.WebApp{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default", UID: "uid-web", Generation: 1},
+		Spec:       v1alpha1.WebAppSpec{Image: "nginx:1.25"},
+	}
+}
+
+func ownedDeployment(webApp *v1alpha1.WebApp, mutate func(d *appsv1.Deployment)) *appsv1.Deployment {
+	d := stack.NewDeployment(newApp(webApp))
+	if mutate != nil {
+		mutate(d)
+	}
+	return d
+}
+
+func ownedService(webApp *v1alpha1.WebApp) *v1.Service {
+	return stack.NewService(newApp(webApp))
+}
+
+// rolledOut 把 deployment 的 status 设置为已经完成 rollout
+func rolledOut(ready int32) func(d *appsv1.Deployment) {
+	return func(d *appsv1.Deployment) {
+		d.Generation = 2
+		d.Status = appsv1.DeploymentStatus{ObservedGeneration: 2, Replicas: ready, UpdatedReplicas: ready, ReadyReplicas: ready}
+	}
+}
+
+type testReconciler struct {
+	*reconciler
+	kubeClient   *fake.Clientset
+	webAppClient *fakeWebAppClient
+	recorder     *record.FakeRecorder
+}
+
+// newTestReconciler 用 existing 同时填充 fake clientset 和 listers 使用的本地存储
+func newTestReconciler(t *testing.T, webApp *v1alpha1.WebApp, existing ...runtime.Object) *testReconciler {
+	t.Helper()
+	deployments := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	services := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	webApps := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{})
+	for _, obj := range existing {
+		indexer := deployments
+		if _, ok := obj.(*v1.Service); ok {
+			indexer = services
+		}
+		if err := indexer.Add(obj); err != nil {
+			t.Fatalf("seed store: %v", err)
+		}
+	}
+	if err := webApps.Add(webApp); err != nil {
+		t.Fatalf("seed store: %v", err)
+	}
+
+	tr := &testReconciler{
+		kubeClient:   fake.NewSimpleClientset(existing...),
+		webAppClient: &fakeWebAppClient{},
+		recorder:     record.NewFakeRecorder(10),
+	}
+	tr.reconciler = &reconciler{
+		kubeClient:   tr.kubeClient,
+		webAppClient: tr.webAppClient,
+		deployments:  appslisters.NewDeploymentLister(deployments),
+		services:     corelisters.NewServiceLister(services),
+		store:        controller.NewStore[*v1alpha1.WebApp](webApps),
+		recorder:     tr.recorder,
+	}
+	return tr
+}
+
+// actions 返回对 kube clientset 的写入, reconciler 只通过 listers 读取
+func (tr *testReconciler) actions() []string {
+	var actions []string
+	for _, action := range tr.kubeClient.Actions() {
+		actions = append(actions, action.GetVerb()+" "+action.GetResource().Resource)
+	}
+	return actions
+}
+
+// events 返回记录的事件的类型和 reason
+func (tr *testReconciler) events() []string {
+	var events []string
+	for {
+		select {
+		case event := <-tr.recorder.Events:
+			fields := strings.Fields(event)
+			events = append(events, fields[0]+" "+fields[1])
+		default:
+			return events
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	webApp := testWebApp()
+	foreign := ownedDeployment(webApp, func(d *appsv1.Deployment) { d.OwnerReferences = nil })
+	drifted := ownedDeployment(webApp, func(d *appsv1.Deployment) {
+		d.Spec.Template.Spec.Containers[0].Env = []v1.EnvVar{{Name: "FOO", Value: "bar"}}
+	})
+	rollingUpdate := ownedDeployment(webApp, func(d *appsv1.Deployment) {
+		rolledOut(1)(d)
+		// 旧 ReplicaSet 的 pod 仍然就绪, 新的 pod 还没有创建
+		d.Status.UpdatedReplicas = 0
+	})
+
+	tests := []struct {
+		name          string
+		existing      []runtime.Object
+		wantErr       bool
+		wantActions   []string
+		wantEvents    []string
+		wantReconcile string
+		wantAvailable string
+	}{
+		{
+			name:          "creates deployment and service",
+			wantActions:   []string{"create deployments", "create services"},
+			wantEvents:    []string{"Normal Created", "Normal Created"},
+			wantReconcile: reasonSynced,
+			wantAvailable: reasonRolloutInProgress,
+		},
+		{
+			name:          "updates drifted deployment",
+			existing:      []runtime.Object{drifted, ownedService(webApp)},
+			wantActions:   []string{"update deployments"},
+			wantEvents:    []string{"Normal Updated"},
+			wantReconcile: reasonSynced,
+			wantAvailable: reasonRolloutInProgress,
+		},
+		{
+			name:          "refuses to adopt a deployment it does not own",
+			existing:      []runtime.Object{foreign},
+			wantErr:       true,
+			wantEvents:    []string{"Warning ResourceExists"},
+			wantReconcile: reasonResourceExists,
+			wantAvailable: reasonReplicasNotReady,
+		},
+		{
+			name:          "available after rollout",
+			existing:      []runtime.Object{ownedDeployment(webApp, rolledOut(1)), ownedService(webApp)},
+			wantReconcile: reasonSynced,
+			wantAvailable: reasonReplicasReady,
+		},
+		{
+			name:          "not available during a rolling update",
+			existing:      []runtime.Object{rollingUpdate, ownedService(webApp)},
+			wantReconcile: reasonSynced,
+			wantAvailable: reasonRolloutInProgress,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := newTestReconciler(t, webApp, tt.existing...)
+
+			_, err := tr.Reconcile(context.Background(), "default/web")
+			var exists *errResourceExists
+			if tt.wantErr != (err != nil) || (tt.wantErr && !errors.As(err, &exists)) {
+				t.Fatalf("Reconcile() error = %v, want errResourceExists: %v", err, tt.wantErr)
+			}
+			if got := tr.actions(); !slices.Equal(got, tt.wantActions) {
+				t.Errorf("actions = %v, want %v", got, tt.wantActions)
+			}
+			if got := tr.events(); !slices.Equal(got, tt.wantEvents) {
+				t.Errorf("events = %v, want %v", got, tt.wantEvents)
+			}
+
+			if len(tr.webAppClient.statusUpdates) != 1 {
+				t.Fatalf("status updates = %d, want 1", len(tr.webAppClient.statusUpdates))
+			}
+			status := tr.webAppClient.statusUpdates[0].Status
+			if status.ObservedGeneration != webApp.Generation {
+				t.Errorf("observedGeneration = %d, want %d", status.ObservedGeneration, webApp.Generation)
+			}
+			for conditionType, want := range map[string]string{
+				v1alpha1.ConditionReconciled: tt.wantReconcile,
+				v1alpha1.ConditionAvailable:  tt.wantAvailable,
+			} {
+				condition := meta.FindStatusCondition(status.Conditions, conditionType)
+				if condition == nil || condition.Reason != want {
+					t.Errorf("%s condition = %+v, want reason %s", conditionType, condition, want)
+				}
+			}
+		})
+	}
+}
+
+func TestReconcileSkipsUnchangedStatus(t *testing.T) {
+	webApp := testWebApp()
+	existing := []runtime.Object{ownedDeployment(webApp, rolledOut(1)), ownedService(webApp)}
+	first := newTestReconciler(t, webApp, existing...)
+	if _, err := first.Reconcile(context.Background(), "default/web"); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	// 第二次处理时 status 已经是最新的
+	synced := first.webAppClient.statusUpdates[0]
+	second := newTestReconciler(t, synced, existing...)
+	if _, err := second.Reconcile(context.Background(), "default/web"); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if n := len(second.webAppClient.statusUpdates); n != 0 {
+		t.Errorf("status updates = %d, want none for an unchanged status", n)
+	}
+	if actions := second.actions(); len(actions) != 0 {
+		t.Errorf("actions = %v, want none", actions)
+	}
+	if events := second.events(); len(events) != 0 {
+		t.Errorf("events = %v, want none", events)
+	}
+}
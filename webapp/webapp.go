@@ -0,0 +1,172 @@
+package webapp
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer/yaml"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/klog"
+	"k8s.io/utils/ptr"
+
+	"github.com/xlcbingo1999/example-client-go/connection"
+	"github.com/xlcbingo1999/example-client-go/controller"
+	"github.com/xlcbingo1999/example-client-go/httpserver"
+	"github.com/xlcbingo1999/example-client-go/webapp/apis/v1alpha1"
+	"github.com/xlcbingo1999/example-client-go/webapp/client"
+)
+
+// crdYAML 是 WebApp 的 CustomResourceDefinition, --install-crd 时通过 server-side apply 提交
+//
+//go:embed config/crd.yaml
+var crdYAML []byte
+
+var crdGVR = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+var (
+	// 启动 controller 之前先安装或更新 CRD
+	InstallCRD    bool
+	AllNamespaces bool
+	Workers       int
+	// 收到退出信号后等待正在处理的 key 完成的最长时间
+	ShutdownTimeout time.Duration
+	// 提供 /metrics 的监听地址, 为空时不启动
+	MetricsAddr string
+	// 提供 /healthz, /readyz 和 /debug 的监听地址, 为空时不启动
+	ProbeAddr string
+)
+
+func init() {
+	// EventRecorder 使用 client-go 的 scheme 查找对象的 GroupVersionKind, 需要能认识 WebApp
+	utilruntime.Must(v1alpha1.AddToScheme(scheme.Scheme))
+}
+
+// RunWebApp 运行 WebApp 的 controller, 把每个 WebApp 展开成同名的 Deployment 和 Service, 并在 status 中报告就绪情况
+func RunWebApp() error {
+	config, err := connection.RESTConfig()
+	if err != nil {
+		return err
+	}
+	kubeClient, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+	webAppClient, err := client.NewForConfig(config)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if InstallCRD {
+		dynamicClient, err := dynamic.NewForConfig(config)
+		if err != nil {
+			return err
+		}
+		if err := installCRD(ctx, dynamicClient); err != nil {
+			return err
+		}
+	}
+
+	// namespace默认为default, 空字符串表示所有 namespace
+	namespace := connection.NamespaceOr(v1.NamespaceDefault)
+	if AllNamespaces {
+		namespace = v1.NamespaceAll
+	}
+	klog.Infof("Watching webapps in namespace %q", namespace)
+
+	factory := informers.NewSharedInformerFactoryWithOptions(kubeClient, 0, informers.WithNamespace(namespace))
+	deployments := factory.Apps().V1().Deployments()
+	services := factory.Core().V1().Services()
+	webApps := client.NewWebAppInformer(webAppClient, namespace, 0, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+
+	recorder, stopRecording := controller.NewEventRecorder(kubeClient, "webapp-demo", controller.EventRateLimit{})
+	defer stopRecording()
+
+	r := &reconciler{
+		kubeClient:   kubeClient,
+		webAppClient: webAppClient,
+		deployments:  deployments.Lister(),
+		services:     services.Lister(),
+	}
+	c, err := controller.NewControllerFromInformer[*v1alpha1.WebApp]("webapp", webApps.Informer(), r,
+		controller.WithDrainTimeout(ShutdownTimeout),
+		controller.WithRecorder(recorder),
+	)
+	if err != nil {
+		return err
+	}
+	// Deployment 和 Service 的 ownerReference 指向 WebApp, 它们的状态变化或被修改时重新处理 WebApp
+	webAppGK := v1alpha1.SchemeGroupVersion.WithKind("WebApp").GroupKind()
+	for _, informer := range []cache.SharedIndexInformer{deployments.Informer(), services.Informer()} {
+		if err := c.Watches(informer, controller.EnqueueOwner(webAppGK, nil)); err != nil {
+			return err
+		}
+	}
+
+	if err := httpserver.StartServers(ctx, MetricsAddr, ProbeAddr, c.RegisterProbes); err != nil {
+		return err
+	}
+	return c.Run(ctx, Workers)
+}
+
+// installCRD 通过 server-side apply 创建或更新 CRD, 然后等待它可以使用
+func installCRD(ctx context.Context, dynamicClient dynamic.Interface) error {
+	obj := &unstructured.Unstructured{}
+	if _, _, err := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme).Decode(crdYAML, nil, obj); err != nil {
+		return fmt.Errorf("decode crd: %w", err)
+	}
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	crds := dynamicClient.Resource(crdGVR)
+	_, err = crds.Patch(ctx, obj.GetName(), types.ApplyPatchType, data, metav1.PatchOptions{
+		FieldManager: "webapp-demo",
+		Force:        ptr.To(true),
+	})
+	if err != nil {
+		return fmt.Errorf("apply crd %s: %w", obj.GetName(), err)
+	}
+	klog.Infof("Applied CRD %s", obj.GetName())
+
+	// CRD 变成 Established 之前 apiserver 还不能提供 WebApp 的接口
+	err = wait.PollUntilContextTimeout(ctx, time.Second, time.Minute, true, func(ctx context.Context) (bool, error) {
+		current, err := crds.Get(ctx, obj.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		conditions, err := controller.UnstructuredConditions(current)
+		if err != nil {
+			return false, err
+		}
+		for _, condition := range conditions {
+			if condition.Type == "Established" && condition.Status == metav1.ConditionTrue {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("wait for crd %s to be established: %w", obj.GetName(), err)
+	}
+	return nil
+}